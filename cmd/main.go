@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"poke/core"
@@ -17,22 +22,44 @@ func main() {
 	opts := parseCLIOptions()
 	args := flag.Args()
 
+	// The first Ctrl-C cancels the run so partial results can be reported; a
+	// second one falls through to the default handler and exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	runner := core.NewRequestRunner(opts)
 
 	switch {
 	case len(args) > 0 && args[0] == "send":
-		handleSend(args, runner)
+		handleSend(ctx, args, runner)
+		return
+	case len(args) > 0 && args[0] == "bench":
+		handleBench(args)
+		return
+	case len(args) > 0 && args[0] == "ws":
+		handleWS(ctx, args, runner)
 		return
 	case opts.Help:
 		printUsage()
 		return
 	case len(args) < 1:
-		fmt.Println("Usage: poke [options] <url>")
+		fmt.Println("Usage: poke [options] <url> [items...]")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	if opts.Workers > opts.Repeat {
+	if opts.Output != "" && (opts.Repeat > 1 || opts.Duration > 0) {
+		util.Error("-o writes a single response: drop --repeat/--duration")
+	}
+	if opts.Continue && opts.Output == "" {
+		util.Error("--continue resumes a download: use it with -o <file>")
+	}
+
+	if opts.Workers > opts.Repeat && opts.Duration == 0 {
 		opts.Workers = opts.Repeat
 	}
 
@@ -59,33 +86,101 @@ func main() {
 		QueryParams: u.Query(),
 		Body:        payload,
 		BodyFile:    opts.DataFile,
+		BodyStdin:   opts.DataStdin && !opts.Editor,
 		Retries:     opts.Retries,
 		Backoff:     opts.Backoff,
 		Meta:        &types.Meta{CreatedAt: time.Now()},
 		Workers:     opts.Workers,
 		Repeat:      opts.Repeat,
+		Rate:        opts.Rate,
+		Duration:    types.Duration(opts.Duration),
+		RampUp:      types.Duration(opts.RampUp),
 		Assert:      &types.Assertions{Status: opts.ExpectStatus},
+		Timeouts:    timeoutsFromFlags(opts),
+		NoKeepAlive: opts.NoKeepAlive,
+		Proxy:       opts.Proxy,
+		NoProxy:     opts.NoProxy,
+		Auth:        core.AuthFromOptions(opts),
+		Sign:        core.SigningFromOptions(opts),
+		SSE:         core.SSEFromOptions(opts),
+	}
+
+	if opts.Schema != "" {
+		req.Assert.Schema, _ = json.Marshal(opts.Schema)
+	}
+
+	if opts.CACert != "" || opts.Cert != "" || opts.Key != "" || opts.Insecure || opts.ServerName != "" {
+		req.TLS = &types.TLSOptions{
+			CACert:     opts.CACert,
+			Cert:       opts.Cert,
+			Key:        opts.Key,
+			Insecure:   opts.Insecure,
+			ServerName: opts.ServerName,
+		}
+	}
+
+	for _, field := range opts.FormFields {
+		part, err := util.ParseFormField(field)
+		if err != nil {
+			util.Error("%v", err)
+		}
+		if opts.Form {
+			if part.File != "" {
+				util.Error("File fields need multipart: drop --form to upload %s", part.File)
+			}
+			if req.Form == nil {
+				req.Form = map[string][]string{}
+			}
+			req.Form[part.Name] = append(req.Form[part.Name], part.Value)
+		} else {
+			req.Multipart = append(req.Multipart, part)
+		}
+	}
+
+	gql, err := core.GraphQLFromOptions(opts)
+	if err != nil {
+		util.Error("%v", err)
+	}
+	req.GraphQL = gql
+
+	if len(args) > 1 {
+		applyRequestItems(req, u, args[1:], opts.Form)
+		fromFile = fromFile && req.BodyFile != ""
+	}
+
+	if err := core.ApplyGraphQL(req); err != nil {
+		util.Error("%v", err)
 	}
 
 	if opts.UserAgent != "" {
 		req.Headers["User-Agent"] = []string{"poke/1.0"}
 	}
 
-	if len(req.Body) > 0 || req.BodyFile != "" || opts.DataStdin {
+	if len(req.Body) > 0 || req.BodyFile != "" || opts.DataStdin || len(req.Form) > 0 || len(req.Multipart) > 0 {
 		req.Method = "POST"
 	}
 
+	// a saved request needs the stdin body itself, not a stream
+	if opts.SavePath != "" && req.BodyStdin {
+		if err := core.BufferBody(req); err != nil {
+			util.Error("%v", err)
+		}
+	}
+
 	req.ContentType = util.DetectContentType(req)
+	if req.ContentType == "" && req.BodyStdin {
+		req.ContentType = core.StdinContentType()
+	}
 	if req.ContentType != "" {
 		req.Headers["Content-Type"] = []string{req.ContentType}
 	}
 
-	if err := runner.Execute(req); err != nil {
+	if err := runner.Execute(ctx, req); err != nil {
 		util.Error("Failed to execute request: %v", err)
 	}
 
 	if opts.SavePath != "" {
-		if fromFile {
+		if fromFile || req.GraphQL != nil {
 			req.Body = ""
 		}
 		if err := runner.SaveRequest(req, opts.SavePath); err != nil {
@@ -97,6 +192,81 @@ func main() {
 	}
 }
 
+// timeoutsFromFlags keeps only the timeouts given explicitly, so saved
+// requests don't pin the CLI defaults over later --connect-timeout etc.
+func timeoutsFromFlags(opts *types.CLIOptions) *types.Timeouts {
+	var t types.Timeouts
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "connect-timeout":
+			t.Connect = types.Duration(opts.ConnectTimeout)
+		case "tls-timeout":
+			t.TLSHandshake = types.Duration(opts.TLSHandshakeTimeout)
+		case "header-timeout":
+			t.ResponseHeader = types.Duration(opts.ResponseHeaderTimeout)
+		case "timeout":
+			t.Total = types.Duration(opts.Timeout)
+		}
+	})
+	if t == (types.Timeouts{}) {
+		return nil
+	}
+	return &t
+}
+
+// applyRequestItems merges HTTPie-style items given after the URL into req.
+func applyRequestItems(req *types.PokeRequest, u *url.URL, items []string, form bool) {
+	parsed, err := util.ParseRequestItems(items, form)
+	if err != nil {
+		util.Error("%v", err)
+	}
+
+	for k, vals := range parsed.Headers {
+		req.Headers[k] = append(req.Headers[k], vals...)
+	}
+
+	if len(parsed.Query) > 0 {
+		for k, vals := range parsed.Query {
+			req.QueryParams[k] = append(req.QueryParams[k], vals...)
+		}
+		u.RawQuery = url.Values(req.QueryParams).Encode()
+		req.FullURL = u.String()
+	}
+
+	for k, vals := range parsed.Form {
+		if req.Form == nil {
+			req.Form = map[string][]string{}
+		}
+		req.Form[k] = append(req.Form[k], vals...)
+	}
+
+	// with --graphql, body fields become query variables
+	if len(parsed.Fields) > 0 && req.GraphQL != nil {
+		if req.GraphQL.Variables == nil {
+			req.GraphQL.Variables = map[string]any{}
+		}
+		util.MergeFields(req.GraphQL.Variables, parsed.Fields)
+	} else if len(parsed.Fields) > 0 {
+		if err := core.BufferBody(req); err != nil {
+			util.Error("%v", err)
+		}
+		body, err := util.MergeJSONBody(req.Body, parsed.Fields)
+		if err != nil {
+			util.Error("%v", err)
+		}
+		// the merged body replaces the file it was read from
+		req.Body = body
+		req.BodyFile = ""
+		hasType := false
+		for k := range req.Headers {
+			hasType = hasType || strings.EqualFold(k, "Content-Type")
+		}
+		if !hasType {
+			req.Headers["Content-Type"] = []string{"application/json"}
+		}
+	}
+}
+
 func parseCLIOptions() *types.CLIOptions {
 	opts := &types.CLIOptions{}
 
@@ -106,18 +276,78 @@ func parseCLIOptions() *types.CLIOptions {
 	flag.StringVar(&opts.Data, "data", "", "Request body payload")
 	flag.StringVar(&opts.DataFile, "data-file", "", "File containing request body payload")
 	flag.BoolVar(&opts.DataStdin, "data-stdin", false, "Read request body from stdin")
+	flag.Func("F", "Multipart form field name=value or name=@file[;type=mime][;filename=name] (repeatable)", func(s string) error {
+		opts.FormFields = append(opts.FormFields, s)
+		return nil
+	})
+	flag.BoolVar(&opts.Form, "form", false, "Send -F fields as application/x-www-form-urlencoded")
 	flag.StringVar(&opts.UserAgent, "A", "poke/1.0", "Set the User-Agent header")
 	flag.StringVar(&opts.UserAgent, "user-agent", "poke/1.0", "Set the User-Agent header")
 	flag.StringVar(&opts.Headers, "H", "", "Request headers (key:value)")
 	flag.StringVar(&opts.Headers, "headers", "", "Request headers (key:value)")
 	flag.IntVar(&opts.Repeat, "repeat", 1, "Number of times to send the request (across all workers)")
 	flag.IntVar(&opts.Workers, "workers", 1, "Number of concurrent workers")
+	flag.Func("rate", "Send at a constant rate, e.g. 50/s or 600/m (requests queue when all workers are busy)", func(s string) error {
+		rate, err := util.ParseRate(s)
+		opts.Rate = rate
+		return err
+	})
+	flag.DurationVar(&opts.Duration, "duration", 0, "Keep sending for this long instead of --repeat times")
+	flag.DurationVar(&opts.RampUp, "ramp-up", 0, "Raise the rate (or start workers) linearly over this period")
 	flag.IntVar(&opts.ExpectStatus, "expect-status", 0, "Expected status code")
+	flag.StringVar(&opts.Schema, "schema", "", "Validate the JSON response body against this JSON Schema file")
+	flag.BoolVar(&opts.UpdateSnapshots, "update-snapshots", false, "With send, record each response as <name>.snap.json instead of diffing against it")
 	flag.IntVar(&opts.Retries, "retry", 1, "Retry request if response status is not 200 or does not match --expect-status")
 	flag.IntVar(&opts.Backoff, "backoff", 1, "Base backoff duration in seconds")
 	flag.BoolVar(&opts.DryRun, "dry-run", false, "Render request but do not send")
 	flag.BoolVar(&opts.Editor, "edit", false, "Open payload in editor")
+	flag.StringVar(&opts.Output, "o", "", "Write the response body to a file")
+	flag.StringVar(&opts.Output, "output", "", "Write the response body to a file")
+	flag.Func("max-body", "Buffer at most this much of the response body, e.g. 1MB (the rest is read and discarded)", func(s string) error {
+		size, err := util.ParseSize(s)
+		opts.MaxBody = size
+		return err
+	})
+	flag.BoolVar(&opts.Continue, "continue", false, "Resume an interrupted -o download with a Range request")
+	flag.BoolVar(&opts.SSE, "sse", false, "Read the response as a Server-Sent Events stream (automatic for text/event-stream)")
+	flag.IntVar(&opts.MaxEvents, "max-events", 0, "Stop an event stream or WebSocket session after this many events/messages")
+	flag.DurationVar(&opts.SSETimeout, "sse-timeout", 0, "Stop listening to an event stream after this long")
+	flag.IntVar(&opts.SSEReconnect, "sse-reconnect", 0, "Reconnect with Last-Event-ID up to this many times when the server ends the stream")
+	flag.DurationVar(&opts.WSTimeout, "ws-timeout", 0, "End a WebSocket session after this long")
+	flag.StringVar(&opts.Subprotocol, "subprotocol", "", "WebSocket subprotocols to request (comma-separated)")
+	flag.StringVar(&opts.GraphQL, "graphql", "", "Send a GraphQL query (inline or a .graphql file); name=value items become variables")
+	flag.StringVar(&opts.Variables, "variables", "", "GraphQL variables as a JSON object")
+	flag.StringVar(&opts.Operation, "operation", "", "GraphQL operationName")
+	flag.StringVar(&opts.BenchOut, "bench-out", "", "Write benchmark samples and summary to a .json or .csv file")
 	flag.StringVar(&opts.SavePath, "save", "", "Save request to file")
+	flag.DurationVar(&opts.Timeout, "timeout", 0, "Total time limit for each request, including reading the body (0 for none)")
+	flag.DurationVar(&opts.ConnectTimeout, "connect-timeout", 10*time.Second, "Time limit for establishing a connection")
+	flag.DurationVar(&opts.TLSHandshakeTimeout, "tls-timeout", 10*time.Second, "Time limit for the TLS handshake")
+	flag.DurationVar(&opts.ResponseHeaderTimeout, "header-timeout", 30*time.Second, "Time limit for waiting on response headers")
+	flag.BoolVar(&opts.NoKeepAlive, "no-keepalive", false, "Open a new connection for every request")
+	flag.StringVar(&opts.CACert, "cacert", "", "CA bundle (PEM) used to verify the server")
+	flag.StringVar(&opts.Cert, "cert", "", "Client certificate (PEM) for mutual TLS")
+	flag.StringVar(&opts.Key, "key", "", "Private key (PEM) for --cert")
+	flag.BoolVar(&opts.Insecure, "k", false, "Skip server certificate verification")
+	flag.BoolVar(&opts.Insecure, "insecure", false, "Skip server certificate verification")
+	flag.StringVar(&opts.ServerName, "servername", "", "Override the TLS server name (SNI) sent to the server")
+	flag.StringVar(&opts.Proxy, "x", "", "Proxy URL (http, https, socks5 or socks5h, may include user:pass@)")
+	flag.StringVar(&opts.Proxy, "proxy", "", "Proxy URL (http, https, socks5 or socks5h, may include user:pass@)")
+	flag.StringVar(&opts.NoProxy, "noproxy", "", "Comma-separated hosts, domains or CIDRs that bypass the proxy ('*' for all)")
+	flag.StringVar(&opts.User, "u", "", "Credentials for basic or digest auth (user:pass)")
+	flag.StringVar(&opts.User, "user", "", "Credentials for basic or digest auth (user:pass)")
+	flag.StringVar(&opts.Bearer, "bearer", "", "Bearer token sent in the Authorization header")
+	flag.BoolVar(&opts.Digest, "digest", false, "Use HTTP Digest auth with the -u credentials")
+	flag.BoolVar(&opts.Netrc, "netrc", false, "Look up credentials for the host in ~/.netrc")
+	flag.StringVar(&opts.NetrcFile, "netrc-file", "", "Look up credentials in this netrc file")
+	flag.StringVar(&opts.Sign, "sign", "", "Sign the request: sigv4 or hmac")
+	flag.StringVar(&opts.AWSRegion, "aws-region", "", "Region for --sign sigv4 (defaults to AWS_REGION)")
+	flag.StringVar(&opts.AWSService, "aws-service", "", "Service name for --sign sigv4, e.g. execute-api or s3")
+	flag.StringVar(&opts.HMACSecret, "hmac-secret", "", "Shared secret for --sign hmac")
+	flag.StringVar(&opts.HMACKeyID, "hmac-key-id", "", "Key id available to the signature format as {key_id}")
+	flag.StringVar(&opts.HMACAlgorithm, "hmac-algo", "sha256", "Hash for --sign hmac: sha256, sha512 or sha1")
+	flag.StringVar(&opts.HMACHeader, "hmac-header", "X-Signature", "Header that receives the HMAC signature")
+	flag.StringVar(&opts.HMACLayout, "hmac-layout", "", "Canonical string to sign, e.g. '{method}\\n{path}\\n{body_sha256}'")
 	flag.BoolVar(&opts.Verbose, "v", false, "Verbose output")
 	flag.BoolVar(&opts.Verbose, "verbose", false, "Verbose output")
 	flag.BoolVar(&opts.Help, "h", false, "Show help message")
@@ -130,21 +360,96 @@ func parseCLIOptions() *types.CLIOptions {
 func printUsage() {
 	fmt.Println("Usage: poke [command] [options] <args>")
 	fmt.Println("Commands:")
-	fmt.Println("  send  <path>  Send request(s) from a file/directory")
+	fmt.Println("  send  <path>  Send request(s) from a file/directory (--update-snapshots records golden responses)")
+	fmt.Println("  bench compare <old.json> <new.json>  Compare two --bench-out reports")
+	fmt.Println("  ws    <url|path>  Open a WebSocket session (stdin lines or scripted messages)")
 	flag.PrintDefaults()
 }
 
-func handleSend(args []string, runner *core.RequestRunnerImpl) {
-	if runner.Opts.Help || len(args) < 2 {
-		fmt.Println("Usage: poke send <path>")
+func handleSend(ctx context.Context, args []string, runner *core.RequestRunnerImpl) {
+	// options may also follow the subcommand: poke send --update-snapshots <path>
+	flag.CommandLine.Parse(args[1:])
+	args = flag.Args()
+	if runner.Opts.Help || len(args) != 1 {
+		fmt.Println("Usage: poke send [--update-snapshots] <path>")
 		os.Exit(1)
 	}
 
-	if err := runner.Collect(args[1]); err != nil {
+	if err := runner.Collect(ctx, args[0]); err != nil {
 		util.Warn("Failed to send request(s): %v", err)
 	}
 }
 
+// handleWS opens a WebSocket session to a URL, reading messages from stdin,
+// or plays the scripted session in a saved request file.
+func handleWS(ctx context.Context, args []string, runner *core.RequestRunnerImpl) {
+	// options may also follow the subcommand: poke ws -H "X-Token: abc" <url>
+	flag.CommandLine.Parse(args[1:])
+	args = flag.Args()
+	if runner.Opts.Help || len(args) != 1 {
+		fmt.Println("Usage: poke ws [options] <ws://url | request.json>")
+		os.Exit(1)
+	}
+
+	var req *types.PokeRequest
+	if strings.HasSuffix(args[0], ".json") {
+		loaded, err := runner.Load(ctx, args[0])
+		if err != nil {
+			util.Error("File '%s' is not a valid request: %v", args[0], err)
+		}
+		req = loaded
+	} else {
+		req = &types.PokeRequest{
+			FullURL: args[0],
+			Headers: util.ParseHeaders(runner.Opts.Headers),
+			Assert:  &types.Assertions{Status: runner.Opts.ExpectStatus},
+		}
+	}
+
+	if err := runner.WebSocket(ctx, req); err != nil {
+		util.Error("WebSocket session failed: %v", err)
+	}
+}
+
+func handleBench(args []string) {
+	fs := flag.NewFlagSet("bench compare", flag.ExitOnError)
+	threshold := fs.String("threshold", "p95=10%", "Allowed regression per metric, e.g. p95=10%,throughput=5%,error_rate=1 (error_rate in percentage points)")
+	usage := func() {
+		fmt.Println("Usage: poke bench compare [--threshold p95=10%] <old.json> <new.json>")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	fs.Usage = usage
+	if len(args) < 2 || args[1] != "compare" {
+		usage()
+	}
+	fs.Parse(args[2:])
+	if fs.NArg() != 2 {
+		usage()
+	}
+
+	thresholds, err := util.ParseThresholds(*threshold)
+	if err != nil {
+		util.Error("%v", err)
+	}
+	old, err := util.ReadBenchmarkReport(fs.Arg(0))
+	if err != nil {
+		util.Error("Failed to read baseline: %v", err)
+	}
+	current, err := util.ReadBenchmarkReport(fs.Arg(1))
+	if err != nil {
+		util.Error("Failed to read results: %v", err)
+	}
+
+	deltas := util.CompareBenchmarkReports(old, current, thresholds)
+	util.PrintBenchmarkComparison(deltas, thresholds)
+	for _, d := range deltas {
+		if d.Exceeded {
+			os.Exit(1)
+		}
+	}
+}
+
 func ensurePokeDir() {
 	homedir, err := os.UserHomeDir()
 	if err != nil {
@@ -0,0 +1,299 @@
+package core
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"poke/types"
+)
+
+// AuthFromOptions builds the auth settings given on the command line, or nil
+// if none were given.
+func AuthFromOptions(opts *types.CLIOptions) *types.Auth {
+	switch {
+	case opts.Bearer != "":
+		return &types.Auth{Type: "bearer", Token: opts.Bearer}
+	case opts.User != "":
+		user, pass, _ := strings.Cut(opts.User, ":")
+		authType := "basic"
+		if opts.Digest {
+			authType = "digest"
+		}
+		return &types.Auth{Type: authType, Username: user, Password: pass}
+	case opts.Netrc || opts.NetrcFile != "":
+		return &types.Auth{Type: "netrc", NetrcFile: opts.NetrcFile}
+	}
+	return nil
+}
+
+// authFor returns the auth settings for req, falling back to the CLI options
+// for saved requests that do not declare their own.
+func (r *RequestRunnerImpl) authFor(req *types.PokeRequest) (*types.Auth, error) {
+	auth := req.Auth
+	if auth == nil {
+		auth = AuthFromOptions(r.Opts)
+	}
+	if auth == nil {
+		return nil, nil
+	}
+	if auth.Type == "" {
+		// default on a copy: req.Auth is shared by every worker
+		a := *auth
+		switch {
+		case a.OAuth2 != nil:
+			a.Type = "oauth2"
+		case a.Username != "":
+			a.Type = "basic"
+		}
+		auth = &a
+	}
+	switch strings.ToLower(auth.Type) {
+	case "basic", "bearer", "digest", "netrc":
+		return auth, nil
+	case "oauth2":
+		if auth.OAuth2 == nil {
+			return nil, fmt.Errorf("oauth2 auth requires an \"oauth2\" block")
+		}
+		return auth, nil
+	}
+	return nil, fmt.Errorf("unknown auth type %q (use basic, bearer, digest, netrc or oauth2)", auth.Type)
+}
+
+// applyAuth sets the Authorization header for schemes that do not need a
+// challenge first. An Authorization header given explicitly is left alone.
+// refresh forces a new OAuth2 token instead of a cached one.
+func (r *RequestRunnerImpl) applyAuth(ctx context.Context, client *http.Client, httpReq *http.Request, auth *types.Auth, refresh bool) error {
+	if auth == nil || (!refresh && httpReq.Header.Get("Authorization") != "") {
+		return nil
+	}
+	switch strings.ToLower(auth.Type) {
+	case "basic":
+		httpReq.SetBasicAuth(auth.Username, auth.Password)
+	case "bearer":
+		if auth.Token == "" {
+			return fmt.Errorf("bearer auth requires a token")
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+auth.Token)
+	case "netrc":
+		login, password, ok, err := netrcLookup(auth.NetrcFile, httpReq.URL.Hostname())
+		if err != nil {
+			return err
+		}
+		if ok {
+			httpReq.SetBasicAuth(login, password)
+		}
+	case "oauth2":
+		token, err := r.oauth2Token(ctx, client, auth.OAuth2, refresh)
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// digestChallenge returns the Digest WWW-Authenticate challenge from a 401
+// response when req uses digest auth, or "" if there is nothing to answer.
+func digestChallenge(resp *http.Response, auth *types.Auth) string {
+	if auth == nil || !strings.EqualFold(auth.Type, "digest") || resp.StatusCode != http.StatusUnauthorized {
+		return ""
+	}
+	for _, v := range resp.Header.Values("WWW-Authenticate") {
+		if len(v) > 7 && strings.EqualFold(v[:7], "digest ") {
+			return v
+		}
+	}
+	return ""
+}
+
+// digestCnonce returns the client nonce; tests replace it to check known vectors.
+var digestCnonce = func() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// digestAuthorization answers a Digest challenge (RFC 7616) for httpReq.
+// MD5 and SHA-256, their -sess variants, and qop "auth" are supported.
+func digestAuthorization(challenge string, auth *types.Auth, httpReq *http.Request) (string, error) {
+	params := parseAuthParams(challenge[7:])
+	realm, nonce := params["realm"], params["nonce"]
+	if nonce == "" {
+		return "", fmt.Errorf("digest challenge has no nonce")
+	}
+
+	algorithm := params["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+	h := func(s string) string {
+		d := newHash()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	cnonce := digestCnonce()
+	nc := "00000001"
+	uri := httpReq.URL.RequestURI()
+
+	ha1 := h(auth.Username + ":" + realm + ":" + auth.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(httpReq.Method + ":" + uri)
+
+	qop := ""
+	for q := range strings.SplitSeq(params["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	if params["qop"] != "" && qop == "" {
+		return "", fmt.Errorf("unsupported digest qop %q", params["qop"])
+	}
+
+	var response string
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, nonce, nc, cnonce, qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	parts := []string{
+		fmt.Sprintf("username=%q", auth.Username),
+		fmt.Sprintf("realm=%q", realm),
+		fmt.Sprintf("nonce=%q", nonce),
+		fmt.Sprintf("uri=%q", uri),
+		fmt.Sprintf("algorithm=%s", algorithm),
+		fmt.Sprintf("response=%q", response),
+	}
+	if qop != "" {
+		parts = append(parts, "qop="+qop, "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	if opaque, ok := params["opaque"]; ok {
+		parts = append(parts, fmt.Sprintf("opaque=%q", opaque))
+	}
+	return "Digest " + strings.Join(parts, ", "), nil
+}
+
+// parseAuthParams splits a comma-separated list of key=value or key="value"
+// auth parameters.
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " ")
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			val = b.String()
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			val = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = val
+	}
+	return params
+}
+
+// netrcLookup finds the login and password for host in a netrc file, which
+// defaults to $NETRC or ~/.netrc. A "default" entry is used if no machine matches.
+func netrcLookup(path, host string) (login, password string, ok bool, err error) {
+	if path == "" {
+		path = os.Getenv("NETRC")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false, err
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to read netrc: %w", err)
+	}
+
+	type entry struct{ login, password string }
+	var match, fallback *entry
+	var current *entry
+	fields := strings.Fields(string(data))
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(fields) {
+				i++
+				if fields[i] == host && match == nil {
+					match = &entry{}
+					current = match
+				}
+			}
+		case "default":
+			current = nil
+			if fallback == nil {
+				fallback = &entry{}
+				current = fallback
+			}
+		case "login", "password", "account":
+			key := fields[i]
+			if i+1 < len(fields) {
+				i++
+			}
+			if current == nil {
+				continue
+			}
+			switch key {
+			case "login":
+				current.login = fields[i]
+			case "password":
+				current.password = fields[i]
+			}
+		case "macdef":
+			// macros run until a blank line, which Fields cannot see; stop here
+			current = nil
+		}
+	}
+	if match == nil {
+		match = fallback
+	}
+	if match == nil {
+		return "", "", false, nil
+	}
+	return match.login, match.password, true, nil
+}
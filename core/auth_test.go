@@ -0,0 +1,87 @@
+package core
+
+import (
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+
+	"poke/types"
+)
+
+func TestParseAuthParams(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{`realm="a", nonce="b"`, map[string]string{"realm": "a", "nonce": "b"}},
+		{`qop="auth,auth-int", algorithm=MD5`, map[string]string{"qop": "auth,auth-int", "algorithm": "MD5"}},
+		{`Realm="with, comma",stale=false`, map[string]string{"realm": "with, comma", "stale": "false"}},
+		{`realm="esc\"aped", opaque=""`, map[string]string{"realm": `esc"aped`, "opaque": ""}},
+		{`realm="unterminated`, map[string]string{"realm": "unterminated"}},
+		{``, map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := parseAuthParams(tt.in); !maps.Equal(got, tt.want) {
+			t.Errorf("parseAuthParams(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// The vectors are the examples in RFC 7616 section 3.9.1.
+func TestDigestAuthorization(t *testing.T) {
+	defer func(orig func() string) { digestCnonce = orig }(digestCnonce)
+	digestCnonce = func() string { return "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ" }
+
+	const challenge = `Digest realm="http-auth@example.org", qop="auth, auth-int", ` +
+		`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", ` +
+		`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`
+	tests := []struct {
+		name      string
+		challenge string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name:      "md5",
+			challenge: challenge + ", algorithm=MD5",
+			want: []string{`response="8ca523f5e9506fed4657c9700eebdbec"`, "qop=auth", "nc=00000001",
+				`cnonce="f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"`, `opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`},
+		},
+		{
+			name:      "sha-256",
+			challenge: challenge + ", algorithm=SHA-256",
+			want:      []string{`response="753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"`, "algorithm=SHA-256"},
+		},
+		{
+			name:      "default algorithm is md5",
+			challenge: challenge,
+			want:      []string{`response="8ca523f5e9506fed4657c9700eebdbec"`, "algorithm=MD5"},
+		},
+		{name: "no nonce", challenge: `Digest realm="x"`, wantErr: "no nonce"},
+		{name: "unsupported algorithm", challenge: challenge + ", algorithm=SHA-512-256", wantErr: "unsupported digest algorithm"},
+		{name: "unsupported qop", challenge: `Digest realm="x", nonce="n", qop="auth-int"`, wantErr: "unsupported digest qop"},
+	}
+
+	auth := &types.Auth{Type: "digest", Username: "Mufasa", Password: "Circle of Life"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "http://www.example.org/dir/index.html", nil)
+			got, err := digestAuthorization(tt.challenge, auth, req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, part := range append(tt.want, `username="Mufasa"`, `uri="/dir/index.html"`) {
+				if !strings.Contains(got, part) {
+					t.Errorf("authorization %q is missing %s", got, part)
+				}
+			}
+		})
+	}
+}
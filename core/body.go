@@ -0,0 +1,221 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"poke/types"
+)
+
+// stdin is read through a buffer so its first bytes can be sniffed for a
+// Content-Type and still be sent.
+var stdin = bufio.NewReader(os.Stdin)
+
+// openBody returns a fresh reader over req's body and its length, or -1 when
+// the length is unknown and the body must be sent chunked. Every call starts
+// from the beginning, so retries and auth round trips never see a drained body.
+// Stdin is the exception: it can only be read once, so Execute buffers it
+// whenever the body may be sent more than once.
+func openBody(req *types.PokeRequest) (io.ReadCloser, int64, error) {
+	switch {
+	case len(req.Multipart) > 0:
+		return openMultipart(req)
+	case len(req.Form) > 0:
+		encoded := url.Values(req.Form).Encode()
+		return io.NopCloser(strings.NewReader(encoded)), int64(len(encoded)), nil
+	case req.Body == "" && req.BodyFile != "":
+		f, err := os.Open(req.BodyFile)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to open body file: %w", err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, fmt.Errorf("failed to read body file: %w", err)
+		}
+		return f, info.Size(), nil
+	case req.Body == "" && req.BodyStdin:
+		return io.NopCloser(stdin), -1, nil
+	default:
+		return io.NopCloser(strings.NewReader(req.Body)), int64(len(req.Body)), nil
+	}
+}
+
+// streamsBody reports whether req's body comes from disk or stdin rather
+// than memory.
+func streamsBody(req *types.PokeRequest) bool {
+	if len(req.Multipart) > 0 {
+		return slices.ContainsFunc(req.Multipart, func(p types.FormPart) bool { return p.File != "" })
+	}
+	return len(req.Form) == 0 && req.Body == "" && (req.BodyFile != "" || req.BodyStdin)
+}
+
+// BufferBody reads a file or stdin body into req.Body, for requests that
+// need the content itself rather than a stream.
+func BufferBody(req *types.PokeRequest) error {
+	if req.Body != "" || (req.BodyFile == "" && !req.BodyStdin) {
+		return nil
+	}
+	var content []byte
+	var err error
+	if req.BodyFile != "" {
+		content, err = os.ReadFile(req.BodyFile)
+	} else {
+		content, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = string(content)
+	req.BodyStdin = false
+	return nil
+}
+
+// StdinContentType sniffs the type of a streamed stdin body from its first
+// bytes, or returns "" when stdin is empty.
+func StdinContentType() string {
+	head, _ := stdin.Peek(512)
+	if len(head) == 0 {
+		return ""
+	}
+	return http.DetectContentType(head)
+}
+
+type liveOutputKey struct{}
+
+// withLiveOutput marks ctx as a single request whose upload and download
+// progress and streamed events are shown as they happen.
+func withLiveOutput(ctx context.Context) context.Context {
+	return context.WithValue(ctx, liveOutputKey{}, true)
+}
+
+func liveOutput(ctx context.Context) bool {
+	live, _ := ctx.Value(liveOutputKey{}).(bool)
+	return live
+}
+
+// openMultipart streams the parts through a pipe so files are copied from
+// disk as the request is written rather than loaded into memory.
+func openMultipart(req *types.PokeRequest) (io.ReadCloser, int64, error) {
+	if req.Boundary == "" {
+		return nil, 0, fmt.Errorf("multipart body has no boundary")
+	}
+	size, err := multipartSize(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		mw := multipart.NewWriter(pw)
+		mw.SetBoundary(req.Boundary)
+		for _, part := range req.Multipart {
+			w, err := mw.CreatePart(partHeader(part))
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if part.File == "" {
+				_, err = io.WriteString(w, part.Value)
+			} else {
+				err = copyFile(w, part.File)
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(mw.Close())
+	}()
+	return pr, size, nil
+}
+
+// multipartSize works out the encoded length up front by rendering only the
+// part headers and adding the value and file sizes.
+func multipartSize(req *types.PokeRequest) (int64, error) {
+	var counter countingWriter
+	mw := multipart.NewWriter(&counter)
+	mw.SetBoundary(req.Boundary)
+	var content int64
+	for _, part := range req.Multipart {
+		if _, err := mw.CreatePart(partHeader(part)); err != nil {
+			return 0, err
+		}
+		if part.File == "" {
+			content += int64(len(part.Value))
+			continue
+		}
+		info, err := os.Stat(part.File)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read form file: %w", err)
+		}
+		content += info.Size()
+	}
+	if err := mw.Close(); err != nil {
+		return 0, err
+	}
+	return counter.n + content, nil
+}
+
+func partHeader(part types.FormPart) textproto.MIMEHeader {
+	h := textproto.MIMEHeader{}
+	if part.File == "" {
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(part.Name)))
+		if part.ContentType != "" {
+			h.Set("Content-Type", part.ContentType)
+		}
+		return h
+	}
+
+	filename := part.Filename
+	if filename == "" {
+		filename = filepath.Base(part.File)
+	}
+	contentType := part.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(part.File))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(part.Name), escapeQuotes(filename)))
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open form file: %w", err)
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"poke/util"
+)
+
+// resumeOffset is the size of the partial download a --continue run picks up
+// from, or 0 when there is nothing to resume.
+func (r *RequestRunnerImpl) resumeOffset() int64 {
+	if !r.Opts.Continue || r.Opts.Output == "" {
+		return 0
+	}
+	info, err := os.Stat(r.Opts.Output)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+	return info.Size()
+}
+
+// download streams resp's body into path and returns the bytes written and
+// the offset they were written at. A 206 reply to a Range request is
+// appended to the partial file; anything else replaces it.
+func download(ctx context.Context, resp *http.Response, path string) (int64, int64, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	var offset int64
+	if resp.StatusCode == http.StatusPartialContent {
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return 0, 0, err
+		}
+		offset = start
+		flags = os.O_WRONLY
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer f.Close()
+	if offset > 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read output file: %w", err)
+		}
+		if offset > info.Size() {
+			return 0, 0, fmt.Errorf("server resumed at byte %d but %s only has %d", offset, path, info.Size())
+		}
+		if err := f.Truncate(offset); err != nil {
+			return 0, 0, fmt.Errorf("failed to truncate output file: %w", err)
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return 0, 0, fmt.Errorf("failed to seek output file: %w", err)
+		}
+	}
+
+	body := resp.Body
+	if liveOutput(ctx) {
+		progress := util.NewTransferProgress("Downloading", resp.ContentLength)
+		progress.Start()
+		body = progress.Reader(body)
+		defer progress.Stop()
+	}
+	n, err := io.Copy(f, body)
+	if err != nil {
+		return n, offset, fmt.Errorf("download interrupted after %s: %w", util.FormatBytes(n), err)
+	}
+	return n, offset, nil
+}
+
+// readBody buffers resp's body up to limit and drains whatever is left, so
+// the returned count is every body byte the server sent, kept or not.
+func readBody(resp *http.Response, limit int64) ([]byte, int64, bool, error) {
+	counter := &countingReader{r: resp.Body}
+	body, truncated, err := util.ReadBody(counter, limit)
+	if err != nil {
+		return nil, counter.n, false, err
+	}
+	if truncated {
+		if _, err := io.Copy(io.Discard, counter); err != nil {
+			return nil, counter.n, true, fmt.Errorf("failed to drain body after %s: %w", util.FormatBytes(counter.n), err)
+		}
+	}
+	return body, counter.n, truncated, nil
+}
+
+// contentRangeStart parses the first byte position from a Content-Range
+// header such as "bytes 100-199/200".
+func contentRangeStart(header string) (int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	start, _, _ := strings.Cut(spec, "-")
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	return n, nil
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadBodyCountsDrainedBytes(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		limit         int64
+		want          string
+		wantTruncated bool
+	}{
+		{"no limit", "hello world", 0, "hello world", false},
+		{"under the limit", "hello", 10, "hello", false},
+		{"at the limit", "hello", 5, "hello", false},
+		{"over the limit", "hello world", 5, "hello", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Body: io.NopCloser(strings.NewReader(tt.body))}
+			body, n, truncated, err := readBody(resp, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("got %q (truncated %v), want %q (truncated %v)", body, truncated, tt.want, tt.wantTruncated)
+			}
+			if n != int64(len(tt.body)) {
+				t.Errorf("counted %d bytes, want %d", n, len(tt.body))
+			}
+		})
+	}
+}
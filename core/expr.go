@@ -0,0 +1,112 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+
+	"poke/types"
+	"poke/util"
+)
+
+// EvalExpressions checks resp against assert.expr entries. Each entry is the
+// inside of a template action, e.g. `eq .status 200` or
+// `eq .body.total (len .body.items)`, and must produce true or false. The
+// braces are left out so the request template does not render them at load.
+func (t *TemplateEngineImpl) EvalExpressions(exprs []string, resp *types.PokeResponse) error {
+	t.LoadEnv()
+	data := exprData(resp)
+	var errs []error
+	for _, expr := range exprs {
+		ok, err := t.evalExpression(expr, data)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("expr %q: %w", expr, err))
+		case !ok:
+			errs = append(errs, fmt.Errorf("expected %q to be true", expr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *TemplateEngineImpl) evalExpression(expr string, data map[string]any) (bool, error) {
+	tmpl, err := template.New("expr").
+		Funcs(sprig.TxtFuncMap()).
+		Funcs(t.funcMap()).
+		Parse("{{ " + expr + " }}")
+	if err != nil {
+		return false, fmt.Errorf("template parse: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return false, fmt.Errorf("template exec: %w", err)
+	}
+	switch out := strings.TrimSpace(buf.String()); out {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected true or false, got %q", out)
+	}
+}
+
+// exprData is what expressions see: .status, .headers (canonical names,
+// values joined with ", "), .body (parsed JSON, or the raw text),
+// .duration and .duration_ms.
+func exprData(resp *types.PokeResponse) map[string]any {
+	headers := map[string]string{}
+	for k, vs := range resp.Headers {
+		headers[http.CanonicalHeaderKey(k)] = strings.Join(vs, ", ")
+	}
+	var body any = string(resp.Body)
+	var parsed any
+	if json.Unmarshal(resp.Body, &parsed) == nil {
+		body = integralNumbers(parsed)
+	}
+	return map[string]any{
+		"status":      resp.StatusCode,
+		"headers":     headers,
+		"body":        body,
+		"duration":    resp.Duration,
+		"duration_ms": resp.Duration.Milliseconds(),
+	}
+}
+
+// integralNumbers turns whole JSON numbers into int64, so comparisons like
+// `eq .body.total (len .body.items)` don't fail on mismatched types.
+func integralNumbers(v any) any {
+	switch node := v.(type) {
+	case float64:
+		if node == math.Trunc(node) && math.Abs(node) < 1<<53 {
+			return int64(node)
+		}
+	case map[string]any:
+		for k, child := range node {
+			node[k] = integralNumbers(child)
+		}
+	case []any:
+		for i, child := range node {
+			node[i] = integralNumbers(child)
+		}
+	}
+	return v
+}
+
+// verify applies the fixed assertions and the expressions in assert,
+// reporting every failure together.
+func (r *RequestRunnerImpl) verify(resp *types.PokeResponse, assert *types.Assertions) (bool, error) {
+	ok, err := util.AssertResponse(resp, assert)
+	if len(assert.Expr) > 0 {
+		err = errors.Join(err, r.Tmpl.EvalExpressions(assert.Expr, resp))
+		ok = err == nil
+	}
+	return ok, err
+}
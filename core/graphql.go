@@ -0,0 +1,83 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"poke/types"
+)
+
+// GraphQLFromOptions builds a graphql block from --graphql, --variables and
+// --operation, or nil when --graphql is not set.
+func GraphQLFromOptions(opts *types.CLIOptions) (*types.GraphQL, error) {
+	if opts.GraphQL == "" {
+		return nil, nil
+	}
+	gql := &types.GraphQL{Query: opts.GraphQL, OperationName: opts.Operation}
+	if opts.Variables != "" {
+		if err := json.Unmarshal([]byte(opts.Variables), &gql.Variables); err != nil {
+			return nil, fmt.Errorf("invalid --variables: %w", err)
+		}
+	}
+	return gql, nil
+}
+
+// ApplyGraphQL wraps req.GraphQL into the JSON envelope and makes it the
+// POST body, reading the query from disk when it names a .graphql/.gql file.
+func ApplyGraphQL(req *types.PokeRequest) error {
+	gql := req.GraphQL
+	if gql == nil {
+		return nil
+	}
+	query := gql.Query
+	if isQueryFile(query) {
+		content, err := os.ReadFile(query)
+		if err != nil {
+			return fmt.Errorf("failed to read graphql query: %w", err)
+		}
+		query = string(content)
+	}
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("graphql requests need a query")
+	}
+
+	envelope := types.GraphQL{Query: query, Variables: gql.Variables, OperationName: gql.OperationName}
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode graphql request: %w", err)
+	}
+	req.Method = "POST"
+	req.Body = string(body)
+	req.BodyFile, req.BodyStdin = "", false
+	if req.Headers == nil {
+		req.Headers = map[string][]string{}
+	}
+	for k := range req.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			delete(req.Headers, k)
+		}
+	}
+	req.Headers["Content-Type"] = []string{"application/json"}
+	return nil
+}
+
+// isQueryFile reports whether a graphql query names a .graphql/.gql file
+// rather than holding the document itself.
+func isQueryFile(query string) bool {
+	ext := strings.ToLower(filepath.Ext(query))
+	return ext == ".graphql" || ext == ".gql"
+}
+
+// graphQLErrors returns the errors array of a GraphQL response body, if any.
+func graphQLErrors(body []byte) []types.GraphQLError {
+	var parsed struct {
+		Errors []types.GraphQLError `json:"errors"`
+	}
+	if json.Unmarshal(body, &parsed) != nil {
+		return nil
+	}
+	return parsed.Errors
+}
@@ -0,0 +1,341 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"poke/types"
+	"poke/util"
+)
+
+// grpcCodes names the status codes from the gRPC spec, indexed by code.
+var grpcCodes = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+const grpcMaxMessage = 32 << 20 // largest response message poke will accept
+
+// sendGRPC makes a unary or server-streaming gRPC (or gRPC-Web) call. The
+// response messages are rendered as JSON: a unary reply becomes the body,
+// streamed replies become events.
+func (r *RequestRunnerImpl) sendGRPC(ctx context.Context, req *types.PokeRequest) (*types.PokeResponse, error) {
+	g := req.GRPC
+	method, err := r.grpcMethod(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	if method.IsStreamingClient() {
+		return nil, fmt.Errorf("%s/%s is client streaming, only unary and server-streaming calls are supported", g.Service, g.Method)
+	}
+
+	in := dynamicpb.NewMessage(method.Input())
+	if len(bytes.TrimSpace(g.Message)) > 0 {
+		if err := protojson.Unmarshal(g.Message, in); err != nil {
+			return nil, fmt.Errorf("invalid %s message: %w", method.Input().FullName(), err)
+		}
+	}
+	payload, err := proto.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request message: %w", err)
+	}
+	frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(payload)))
+	frame = append(frame, payload...)
+
+	u, err := url.Parse(req.FullURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
+	}
+	u.Path = "/" + g.Service + "/" + g.Method
+	u.RawQuery = ""
+
+	call := *req
+	call.FullURL = u.String()
+	call.Method = http.MethodPost
+	call.Body, call.BodyFile, call.BodyStdin = string(frame), "", false
+	call.Form, call.Multipart, call.SSE = nil, nil, nil
+	call.Headers = grpcHeaders(req.Headers, g.Web)
+
+	client, err := r.client(&call)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rawResp, httpReq, trace, err := r.do(ctx, client, &call)
+	duration := time.Since(start)
+	if err != nil {
+		return nil, describeTLSError(err)
+	}
+
+	resp := &types.PokeResponse{
+		StatusCode:  rawResp.StatusCode,
+		Status:      rawResp.Status,
+		Headers:     rawResp.Header,
+		ContentType: "application/json",
+		Raw:         rawResp,
+		Duration:    duration,
+		Proxy:       proxyFor(client, httpReq),
+	}
+	if ct := rawResp.Header.Get("Content-Type"); rawResp.StatusCode != http.StatusOK || !strings.HasPrefix(ct, "application/grpc") {
+		// not a gRPC reply at all, e.g. a proxy error page
+		if resp.Body, resp.Bytes, resp.Truncated, err = readBody(rawResp, r.Opts.MaxBody); err != nil {
+			return nil, err
+		}
+		resp.ContentType = ct
+		resp.Timestamp = time.Now()
+		resp.Timings = trace.timings(resp.Timestamp)
+		resp.GRPC = httpStatusToGRPC(rawResp.StatusCode, ct)
+		return resp, nil
+	}
+
+	live := liveOutput(ctx) && method.IsStreamingServer()
+	counter := &countingReader{r: rawResp.Body}
+	trailers, messages, err := readGRPCFrames(counter, rawResp.Header.Get("Grpc-Encoding"), method.Output(), func(msg []byte) {
+		if live {
+			util.PrintEvent(types.StreamEvent{Event: "message", Data: string(msg)})
+		}
+	})
+	resp.Bytes = counter.n
+	resp.Timestamp = time.Now()
+	resp.Timings = trace.timings(resp.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	// trailers arrive as HTTP/2 trailers, in a gRPC-Web trailer frame, or in
+	// the headers of a trailers-only reply
+	resp.Trailers = map[string][]string{}
+	maps.Copy(resp.Trailers, rawResp.Trailer)
+	maps.Copy(resp.Trailers, trailers)
+	status := textproto.MIMEHeader(resp.Trailers)
+	if status.Get("Grpc-Status") == "" {
+		status = textproto.MIMEHeader(rawResp.Header)
+	}
+	resp.GRPC = grpcStatus(status.Get("Grpc-Status"), status.Get("Grpc-Message"))
+
+	if method.IsStreamingServer() {
+		resp.Events = []types.StreamEvent{}
+		for _, msg := range messages {
+			resp.Events = append(resp.Events, types.StreamEvent{Event: "message", Data: string(msg), Received: resp.Timestamp})
+		}
+		resp.Body = append(append([]byte("["), bytes.Join(messages, []byte(","))...), ']')
+	} else if len(messages) > 0 {
+		resp.Body = messages[0]
+	}
+	return resp, nil
+}
+
+// grpcHeaders copies the user's headers and sets the ones the protocol
+// needs, replacing any Content-Type whatever its case.
+func grpcHeaders(user map[string][]string, web bool) map[string][]string {
+	headers := map[string][]string{}
+	for k, v := range user {
+		if !strings.EqualFold(k, "Content-Type") {
+			headers[k] = v
+		}
+	}
+	if web {
+		headers["Content-Type"] = []string{"application/grpc-web+proto"}
+		headers["X-Grpc-Web"] = []string{"1"}
+	} else {
+		headers["Content-Type"] = []string{"application/grpc+proto"}
+		headers["Te"] = []string{"trailers"}
+	}
+	headers["Grpc-Accept-Encoding"] = []string{"gzip"}
+	return headers
+}
+
+// readGRPCFrames decodes length-prefixed messages from body into JSON,
+// calling onMessage for each. gRPC-Web trailer frames are returned as trailers.
+func readGRPCFrames(body io.Reader, encoding string, desc protoreflect.MessageDescriptor, onMessage func([]byte)) (map[string][]string, [][]byte, error) {
+	br := bufio.NewReader(body)
+	var messages [][]byte
+	trailers := map[string][]string{}
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if err == io.EOF {
+				return trailers, messages, nil
+			}
+			return trailers, messages, fmt.Errorf("failed to read gRPC frame: %w", err)
+		}
+		flags, size := header[0], binary.BigEndian.Uint32(header[1:])
+		if size > grpcMaxMessage {
+			return trailers, messages, fmt.Errorf("gRPC message larger than %s", util.FormatBytes(grpcMaxMessage))
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return trailers, messages, fmt.Errorf("failed to read gRPC frame: %w", err)
+		}
+
+		if flags&0x01 != 0 {
+			if encoding != "gzip" {
+				return trailers, messages, fmt.Errorf("unsupported gRPC message encoding %q", encoding)
+			}
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return trailers, messages, fmt.Errorf("failed to decompress gRPC message: %w", err)
+			}
+			if data, err = io.ReadAll(io.LimitReader(zr, grpcMaxMessage)); err != nil {
+				return trailers, messages, fmt.Errorf("failed to decompress gRPC message: %w", err)
+			}
+		}
+
+		if flags&0x80 != 0 {
+			tp := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(data), strings.NewReader("\r\n"))))
+			parsed, err := tp.ReadMIMEHeader()
+			if err != nil && err != io.EOF {
+				return trailers, messages, fmt.Errorf("invalid gRPC-Web trailers: %w", err)
+			}
+			maps.Copy(trailers, parsed)
+			continue
+		}
+
+		msg := dynamicpb.NewMessage(desc)
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return trailers, messages, fmt.Errorf("failed to decode %s: %w", desc.FullName(), err)
+		}
+		out, err := protojson.Marshal(msg)
+		if err != nil {
+			return trailers, messages, err
+		}
+		messages = append(messages, out)
+		onMessage(out)
+	}
+}
+
+func grpcStatus(code, message string) *types.GRPCStatus {
+	status := &types.GRPCStatus{Code: 2, Name: "UNKNOWN"}
+	if code == "" {
+		status.Message = "no grpc-status in response"
+		return status
+	}
+	if n, err := strconv.Atoi(code); err == nil {
+		status.Code = n
+		if n >= 0 && n < len(grpcCodes) {
+			status.Name = grpcCodes[n]
+		}
+	}
+	// grpc-message is percent-encoded
+	if decoded, err := url.PathUnescape(message); err == nil {
+		message = decoded
+	}
+	status.Message = message
+	return status
+}
+
+// httpStatusToGRPC maps a reply that is not gRPC to a status code the way
+// grpc-go does, so it fails the default OK expectation.
+func httpStatusToGRPC(code int, contentType string) *types.GRPCStatus {
+	n := 2 // UNKNOWN
+	switch code {
+	case http.StatusNotFound:
+		n = 12 // UNIMPLEMENTED
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		n = 14 // UNAVAILABLE
+	case http.StatusUnauthorized:
+		n = 16 // UNAUTHENTICATED
+	case http.StatusForbidden:
+		n = 7 // PERMISSION_DENIED
+	}
+	msg := fmt.Sprintf("unexpected HTTP status %d", code)
+	if code == http.StatusOK {
+		msg = fmt.Sprintf("unexpected content-type %q", contentType)
+	}
+	return &types.GRPCStatus{Code: n, Name: grpcCodes[n], Message: msg}
+}
+
+// grpcMethod resolves the method descriptor for g, compiling protos or
+// loading the descriptor set once per runner.
+func (r *RequestRunnerImpl) grpcMethod(ctx context.Context, g *types.GRPCOptions) (protoreflect.MethodDescriptor, error) {
+	if g.Service == "" || g.Method == "" {
+		return nil, fmt.Errorf("grpc requests need a service and a method")
+	}
+	key := strings.Join([]string{strings.Join(g.Protos, ","), strings.Join(g.ImportPaths, ","), g.DescriptorSet, g.Service, g.Method}, "|")
+	r.descMu.Lock()
+	defer r.descMu.Unlock()
+	if md, ok := r.methods[key]; ok {
+		return md, nil
+	}
+
+	var find func(protoreflect.FullName) (protoreflect.Descriptor, error)
+	switch {
+	case g.DescriptorSet != "":
+		data, err := os.ReadFile(g.DescriptorSet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &set); err != nil {
+			return nil, fmt.Errorf("invalid descriptor set: %w", err)
+		}
+		files, err := protodesc.NewFiles(&set)
+		if err != nil {
+			return nil, fmt.Errorf("invalid descriptor set: %w", err)
+		}
+		find = files.FindDescriptorByName
+	case len(g.Protos) > 0:
+		importPaths, names := g.ImportPaths, g.Protos
+		if len(importPaths) == 0 {
+			// each file is found relative to its own directory, so sibling
+			// imports resolve without any configuration
+			names = nil
+			for _, p := range g.Protos {
+				importPaths = append(importPaths, filepath.Dir(p))
+				names = append(names, filepath.Base(p))
+			}
+		}
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+		}
+		files, err := compiler.Compile(ctx, names...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile protos: %w", err)
+		}
+		find = files.AsResolver().FindDescriptorByName
+	default:
+		return nil, fmt.Errorf("grpc requests need protos or a descriptor_set")
+	}
+
+	desc, err := find(protoreflect.FullName(g.Service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found: %w", g.Service, err)
+	}
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", g.Service)
+	}
+	md := service.Methods().ByName(protoreflect.Name(g.Method))
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found in %s", g.Method, g.Service)
+	}
+	if r.methods == nil {
+		r.methods = map[string]protoreflect.MethodDescriptor{}
+	}
+	r.methods[key] = md
+	return md, nil
+}
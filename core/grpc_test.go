@@ -0,0 +1,154 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"maps"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"poke/types"
+)
+
+// grpcFrame builds a length-prefixed gRPC frame with the given flags byte.
+func grpcFrame(flags byte, data []byte) []byte {
+	frame := binary.BigEndian.AppendUint32([]byte{flags}, uint32(len(data)))
+	return append(frame, data...)
+}
+
+func TestReadGRPCFrames(t *testing.T) {
+	msg := func(name string) []byte {
+		data, _ := proto.Marshal(&descriptorpb.FileDescriptorProto{Name: proto.String(name)})
+		return data
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(msg("zipped"))
+	zw.Close()
+	trailer := grpcFrame(0x80, []byte("grpc-status: 5\r\ngrpc-message: not%20found\r\n"))
+
+	tests := []struct {
+		name         string
+		body         []byte
+		encoding     string
+		want         []string
+		wantTrailers map[string][]string
+		wantErr      string
+	}{
+		{name: "unary", body: grpcFrame(0, msg("a")), want: []string{`{"name":"a"}`}},
+		{name: "stream", body: append(grpcFrame(0, msg("a")), grpcFrame(0, msg("b"))...), want: []string{`{"name":"a"}`, `{"name":"b"}`}},
+		{name: "empty message", body: grpcFrame(0, nil), want: []string{`{}`}},
+		{name: "gzip", body: grpcFrame(1, gz.Bytes()), encoding: "gzip", want: []string{`{"name":"zipped"}`}},
+		{
+			name:         "grpc-web trailers",
+			body:         append(grpcFrame(0, msg("a")), trailer...),
+			want:         []string{`{"name":"a"}`},
+			wantTrailers: map[string][]string{"Grpc-Status": {"5"}, "Grpc-Message": {"not%20found"}},
+		},
+		{name: "no frames", body: nil},
+		{name: "compressed without encoding", body: grpcFrame(1, gz.Bytes()), wantErr: `unsupported gRPC message encoding ""`},
+		{name: "bad gzip", body: grpcFrame(1, []byte("nope")), encoding: "gzip", wantErr: "failed to decompress"},
+		{name: "truncated header", body: []byte{0, 0, 0}, wantErr: "failed to read gRPC frame"},
+		{name: "truncated message", body: grpcFrame(0, msg("a"))[:7], wantErr: "failed to read gRPC frame"},
+		{name: "oversized", body: binary.BigEndian.AppendUint32([]byte{0}, grpcMaxMessage+1), wantErr: "larger than"},
+		{name: "invalid protobuf", body: grpcFrame(0, []byte{0xff}), wantErr: "failed to decode"},
+	}
+	desc := (&descriptorpb.FileDescriptorProto{}).ProtoReflect().Descriptor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen int
+			trailers, messages, err := readGRPCFrames(bytes.NewReader(tt.body), tt.encoding, desc, func([]byte) { seen++ })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != len(tt.want) || seen != len(tt.want) {
+				t.Fatalf("got %d messages (%d callbacks), want %d", len(messages), seen, len(tt.want))
+			}
+			for i, m := range messages {
+				// protojson output is not byte-stable, so compare without spaces
+				if got := strings.ReplaceAll(string(m), " ", ""); got != tt.want[i] {
+					t.Errorf("message %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+			if tt.wantTrailers == nil {
+				tt.wantTrailers = map[string][]string{}
+			}
+			if !maps.EqualFunc(trailers, tt.wantTrailers, func(a, b []string) bool { return strings.Join(a, ",") == strings.Join(b, ",") }) {
+				t.Errorf("trailers = %v, want %v", trailers, tt.wantTrailers)
+			}
+		})
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	tests := []struct {
+		code, message string
+		want          types.GRPCStatus
+	}{
+		{"0", "", types.GRPCStatus{Code: 0, Name: "OK"}},
+		{"5", "not%20found", types.GRPCStatus{Code: 5, Name: "NOT_FOUND", Message: "not found"}},
+		{"16", "bad%zztoken", types.GRPCStatus{Code: 16, Name: "UNAUTHENTICATED", Message: "bad%zztoken"}},
+		{"99", "", types.GRPCStatus{Code: 99, Name: "UNKNOWN"}},
+		{"", "", types.GRPCStatus{Code: 2, Name: "UNKNOWN", Message: "no grpc-status in response"}},
+	}
+	for _, tt := range tests {
+		if got := grpcStatus(tt.code, tt.message); *got != tt.want {
+			t.Errorf("grpcStatus(%q, %q) = %+v, want %+v", tt.code, tt.message, *got, tt.want)
+		}
+	}
+}
+
+func TestHTTPStatusToGRPC(t *testing.T) {
+	tests := []struct {
+		status   int
+		wantName string
+		wantMsg  string
+	}{
+		{404, "UNIMPLEMENTED", "unexpected HTTP status 404"},
+		{502, "UNAVAILABLE", "unexpected HTTP status 502"},
+		{503, "UNAVAILABLE", "unexpected HTTP status 503"},
+		{504, "UNAVAILABLE", "unexpected HTTP status 504"},
+		{401, "UNAUTHENTICATED", "unexpected HTTP status 401"},
+		{403, "PERMISSION_DENIED", "unexpected HTTP status 403"},
+		{500, "UNKNOWN", "unexpected HTTP status 500"},
+		{200, "UNKNOWN", `unexpected content-type "text/html"`},
+	}
+	for _, tt := range tests {
+		got := httpStatusToGRPC(tt.status, "text/html")
+		if got.Name != tt.wantName || got.Message != tt.wantMsg || grpcCodes[got.Code] != got.Name {
+			t.Errorf("httpStatusToGRPC(%d) = %+v, want %s %q", tt.status, *got, tt.wantName, tt.wantMsg)
+		}
+	}
+}
+
+func TestGRPCHeadersReplaceContentType(t *testing.T) {
+	user := map[string][]string{"content-type": {"application/json"}, "X-Api-Key": {"k"}}
+	for _, web := range []bool{false, true} {
+		got := grpcHeaders(user, web)
+		var cts []string
+		for k, v := range got {
+			if strings.EqualFold(k, "Content-Type") {
+				cts = append(cts, v...)
+			}
+		}
+		if len(cts) != 1 || !strings.HasPrefix(cts[0], "application/grpc") {
+			t.Errorf("web=%v: content types %v", web, cts)
+		}
+		if got["X-Api-Key"][0] != "k" {
+			t.Errorf("web=%v: user header dropped", web)
+		}
+	}
+	if _, ok := user["Content-Type"]; ok || len(user) != 2 {
+		t.Error("grpcHeaders changed the caller's headers")
+	}
+}
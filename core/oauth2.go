@@ -0,0 +1,234 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"poke/types"
+)
+
+// oauth2ExpiryMargin renews tokens slightly before they expire.
+const oauth2ExpiryMargin = 30 * time.Second
+
+// cachedToken is an access token stored in ~/.poke/oauth2_tokens.json.
+type cachedToken struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitzero"`
+}
+
+func (t cachedToken) valid() bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || time.Until(t.Expiry) > oauth2ExpiryMargin)
+}
+
+// oauth2Token returns an access token for cfg, from the cache when it is
+// still valid. refresh skips the cache, e.g. after the server answered 401.
+func (r *RequestRunnerImpl) oauth2Token(ctx context.Context, client *http.Client, cfg *types.OAuth2, refresh bool) (string, error) {
+	r.tokenMu.Lock()
+	defer r.tokenMu.Unlock()
+
+	if r.tokens == nil {
+		r.tokens = loadTokenCache()
+	}
+	key := oauth2CacheKey(cfg)
+	cached := r.tokens[key]
+	if !refresh && cached.valid() {
+		return cached.AccessToken, nil
+	}
+
+	grant := cfg.GrantType
+	if grant == "" {
+		grant = "client_credentials"
+	}
+	if grant != "client_credentials" && grant != "refresh_token" {
+		return "", fmt.Errorf("unsupported oauth2 grant type %q", cfg.GrantType)
+	}
+
+	// Prefer refreshing when a refresh token is known; client credentials can
+	// always start over if the refresh token has been revoked.
+	refreshToken := cached.RefreshToken
+	if refreshToken == "" {
+		refreshToken = cfg.RefreshToken
+	}
+	var token cachedToken
+	var err error
+	if refreshToken != "" {
+		token, err = fetchToken(ctx, client, cfg, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {refreshToken},
+		})
+		if err == nil && token.RefreshToken == "" {
+			token.RefreshToken = refreshToken
+		}
+	}
+	if refreshToken == "" || err != nil {
+		if grant == "refresh_token" {
+			if err == nil {
+				err = fmt.Errorf("oauth2 refresh_token grant requires a refresh_token")
+			}
+			return "", err
+		}
+		token, err = fetchToken(ctx, client, cfg, url.Values{"grant_type": {"client_credentials"}})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	r.tokens[key] = token
+	saveTokenCache(r.tokens)
+	return token.AccessToken, nil
+}
+
+// oauth2Rejected reports whether a 401 should be retried with a new token.
+// An explicit Authorization header is only retried when it was rendered from
+// {{ auth.token }}.
+func oauth2Rejected(resp *http.Response, auth *types.Auth, req *types.PokeRequest) bool {
+	if auth == nil || !strings.EqualFold(auth.Type, "oauth2") || resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	if req.AuthToken != "" {
+		return true
+	}
+	for k := range req.Headers {
+		if strings.EqualFold(k, "Authorization") {
+			return false
+		}
+	}
+	return true
+}
+
+// swapAuthToken replaces the token rendered into req's headers at load time
+// with the current one, so long runs pick up renewed tokens. refresh fetches
+// a new token regardless of the cache.
+func (r *RequestRunnerImpl) swapAuthToken(ctx context.Context, client *http.Client, httpReq *http.Request, req *types.PokeRequest, refresh bool) error {
+	if req.AuthToken == "" || req.Auth == nil || req.Auth.OAuth2 == nil {
+		return nil
+	}
+	token, err := r.oauth2Token(ctx, client, req.Auth.OAuth2, refresh)
+	if err != nil || token == req.AuthToken {
+		return err
+	}
+	for _, values := range httpReq.Header {
+		for i, v := range values {
+			values[i] = strings.ReplaceAll(v, req.AuthToken, token)
+		}
+	}
+	return nil
+}
+
+// fetchToken posts a token request to cfg.TokenURL.
+func fetchToken(ctx context.Context, client *http.Client, cfg *types.OAuth2, form url.Values) (cachedToken, error) {
+	if cfg.TokenURL == "" {
+		return cachedToken{}, fmt.Errorf("oauth2 requires a token_url")
+	}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	basic := !strings.EqualFold(cfg.AuthStyle, "params")
+	if !basic {
+		form.Set("client_id", cfg.ClientID)
+		if cfg.ClientSecret != "" {
+			form.Set("client_secret", cfg.ClientSecret)
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return cachedToken{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if basic {
+		httpReq.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return cachedToken{}, fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return cachedToken{}, fmt.Errorf("failed to read oauth2 token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return cachedToken{}, fmt.Errorf("oauth2 token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var parsed struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return cachedToken{}, fmt.Errorf("invalid oauth2 token response: %w", err)
+	}
+	if parsed.AccessToken == "" {
+		return cachedToken{}, fmt.Errorf("oauth2 token response has no access_token")
+	}
+	token := cachedToken{
+		AccessToken:  parsed.AccessToken,
+		TokenType:    parsed.TokenType,
+		RefreshToken: parsed.RefreshToken,
+	}
+	if parsed.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(parsed.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// oauth2CacheKey identifies a token by where and for whom it was issued.
+func oauth2CacheKey(cfg *types.OAuth2) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		cfg.TokenURL, cfg.ClientID, strings.Join(cfg.Scopes, " "), cfg.GrantType,
+	}, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func tokenCachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".poke", "oauth2_tokens.json"), nil
+}
+
+func loadTokenCache() map[string]cachedToken {
+	tokens := map[string]cachedToken{}
+	path, err := tokenCachePath()
+	if err != nil {
+		return tokens
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tokens
+	}
+	json.Unmarshal(data, &tokens)
+	return tokens
+}
+
+// saveTokenCache writes the cache readable only by the current user. Failing
+// to persist is not fatal; the token is still used for this run.
+func saveTokenCache(tokens map[string]cachedToken) {
+	path, err := tokenCachePath()
+	if err != nil {
+		return
+	}
+	out, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(path, out, 0600)
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"poke/types"
+)
+
+// tokenServer is a stand-in OAuth2 token endpoint that issues tok-1, tok-2, ...
+type tokenServer struct {
+	mu     sync.Mutex
+	grants []string
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if id != "client" || secret != "secret" {
+		http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	r.ParseForm()
+	s.mu.Lock()
+	s.grants = append(s.grants, r.PostForm.Get("grant_type"))
+	n := len(s.grants)
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token":  fmt.Sprintf("tok-%d", n),
+		"token_type":    "Bearer",
+		"refresh_token": "refresh-1",
+		"expires_in":    3600,
+	})
+}
+
+func (s *tokenServer) seen() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.grants)
+}
+
+func TestOAuth2TokenFlow(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".poke"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := &tokenServer{}
+	tokenSrv := httptest.NewServer(tokens)
+	defer tokenSrv.Close()
+
+	var mu sync.Mutex
+	accepted := "Bearer tok-1"
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != accepted {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer api.Close()
+
+	runner := NewRequestRunner(&types.CLIOptions{})
+	send := func() int {
+		t.Helper()
+		req := &types.PokeRequest{
+			Method:  http.MethodGet,
+			FullURL: api.URL,
+			Auth: &types.Auth{Type: "oauth2", OAuth2: &types.OAuth2{
+				TokenURL: tokenSrv.URL, ClientID: "client", ClientSecret: "secret",
+			}},
+		}
+		resp, err := runner.Send(context.Background(), req)
+		if err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		resp.Raw.Body.Close()
+		return resp.StatusCode
+	}
+
+	// the first request fetches a token with client credentials
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("first request: got %d, want 200", code)
+	}
+	if got := tokens.seen(); !slices.Equal(got, []string{"client_credentials"}) {
+		t.Fatalf("after first request: grants %v", got)
+	}
+
+	// the second is served from the cache
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("cached request: got %d, want 200", code)
+	}
+	if got := tokens.seen(); len(got) != 1 {
+		t.Fatalf("cached request fetched a token: grants %v", got)
+	}
+
+	// once the server rejects the cached token, a 401 refreshes it and retries
+	mu.Lock()
+	accepted = "Bearer tok-2"
+	mu.Unlock()
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("request after rotation: got %d, want 200", code)
+	}
+	if got := tokens.seen(); !slices.Equal(got, []string{"client_credentials", "refresh_token"}) {
+		t.Fatalf("after 401: grants %v, want a refresh", got)
+	}
+
+	// a new run reads the refreshed token from the on-disk cache
+	runner = NewRequestRunner(&types.CLIOptions{})
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("new runner: got %d, want 200", code)
+	}
+	if got := tokens.seen(); len(got) != 2 {
+		t.Fatalf("new runner fetched a token: grants %v", got)
+	}
+}
+
+func TestOAuth2TemplatedToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".poke"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := &tokenServer{}
+	tokenSrv := httptest.NewServer(tokens)
+	defer tokenSrv.Close()
+
+	var mu sync.Mutex
+	accepted, sent := "Bearer tok-1", []string{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != accepted {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "req.json")
+	file := fmt.Sprintf(`{"method": "GET", "host": %q, "headers": {"Authorization": ["Bearer {{ auth.token }}"]},
+		"auth": {"oauth2": {"token_url": %q, "client_id": "client", "client_secret": "secret"}}}`,
+		strings.TrimPrefix(api.URL, "http://"), tokenSrv.URL)
+	if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := NewRequestRunner(&types.CLIOptions{})
+	req, err := runner.Load(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func() int {
+		t.Helper()
+		resp, err := runner.Send(context.Background(), req)
+		if err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		resp.Raw.Body.Close()
+		return resp.StatusCode
+	}
+	check := func(step string, wantCode int, wantSent ...string) {
+		t.Helper()
+		if code := send(); code != wantCode {
+			t.Fatalf("%s: got %d, want %d", step, code, wantCode)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !slices.Equal(sent, wantSent) {
+			t.Fatalf("%s: sent %v, want %v", step, sent, wantSent)
+		}
+		sent = nil
+	}
+
+	check("rendered token", http.StatusOK, "Bearer tok-1")
+
+	// a 401 refreshes the token and retries with it substituted into the header
+	mu.Lock()
+	accepted = "Bearer tok-2"
+	mu.Unlock()
+	check("after 401", http.StatusOK, "Bearer tok-1", "Bearer tok-2")
+
+	// an expired token is renewed before sending, without a 401 first
+	runner.tokenMu.Lock()
+	key := oauth2CacheKey(req.Auth.OAuth2)
+	expired := runner.tokens[key]
+	expired.Expiry = time.Now()
+	runner.tokens[key] = expired
+	runner.tokenMu.Unlock()
+	mu.Lock()
+	accepted = "Bearer tok-3"
+	mu.Unlock()
+	check("after expiry", http.StatusOK, "Bearer tok-3")
+}
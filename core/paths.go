@@ -0,0 +1,55 @@
+package core
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"poke/types"
+)
+
+// resolvePaths makes every relative file path in a loaded request relative
+// to dir, the request file's directory, so a saved request runs the same
+// from any working directory.
+func resolvePaths(req *types.PokeRequest, dir string) {
+	resolve := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+
+	resolve(&req.BodyFile)
+	for i := range req.Multipart {
+		resolve(&req.Multipart[i].File)
+	}
+	if req.GraphQL != nil && isQueryFile(req.GraphQL.Query) {
+		resolve(&req.GraphQL.Query)
+	}
+	if g := req.GRPC; g != nil {
+		// with import paths set, protos are named relative to those instead
+		for i := range g.ImportPaths {
+			resolve(&g.ImportPaths[i])
+		}
+		if len(g.ImportPaths) == 0 {
+			for i := range g.Protos {
+				resolve(&g.Protos[i])
+			}
+		}
+		resolve(&g.DescriptorSet)
+	}
+	if t := req.TLS; t != nil {
+		resolve(&t.CACert)
+		resolve(&t.Cert)
+		resolve(&t.Key)
+	}
+	if req.Auth != nil {
+		resolve(&req.Auth.NetrcFile)
+	}
+	if req.Assert != nil && len(req.Assert.Schema) > 0 {
+		// a string schema is a file path; an object is the schema itself
+		var path string
+		if json.Unmarshal(req.Assert.Schema, &path) == nil {
+			resolve(&path)
+			req.Assert.Schema, _ = json.Marshal(path)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"poke/types"
+)
+
+func TestLoadResolvesPathsAgainstRequestFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"body.json":       `{"a": 1}`,
+		"query.graphql":   `{ me { id } }`,
+		"schemas/x.json":  `{}`,
+		"upload/file.txt": `data`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	abs := filepath.Join(dir, "abs.pem")
+	req := types.PokeRequest{
+		Method:    "POST",
+		Host:      "example.com",
+		BodyFile:  "body.json",
+		Multipart: []types.FormPart{{Name: "f", File: "upload/file.txt"}, {Name: "v", Value: "x"}},
+		GRPC:      &types.GRPCOptions{Protos: []string{"protos/a.proto"}, DescriptorSet: "set.pb"},
+		TLS:       &types.TLSOptions{CACert: "certs/ca.pem", Cert: abs},
+		Auth:      &types.Auth{Type: "netrc", NetrcFile: ".netrc"},
+		Assert:    &types.Assertions{Schema: json.RawMessage(`"schemas/x.json"`)},
+	}
+	gql := types.PokeRequest{Host: "example.com", GraphQL: &types.GraphQL{Query: "query.graphql"}}
+	for name, r := range map[string]types.PokeRequest{"req.json": req, "gql.json": gql} {
+		data, _ := json.Marshal(r)
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewRequestRunner(&types.CLIOptions{})
+	got, err := r.Load(context.Background(), filepath.Join(dir, "req.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := func(name string) string { return filepath.Join(dir, name) }
+	for _, tt := range []struct{ field, got, want string }{
+		{"body_file", got.BodyFile, in("body.json")},
+		{"multipart file", got.Multipart[0].File, in("upload/file.txt")},
+		{"multipart value", got.Multipart[1].File, ""},
+		{"proto", got.GRPC.Protos[0], in("protos/a.proto")},
+		{"descriptor_set", got.GRPC.DescriptorSet, in("set.pb")},
+		{"ca_cert", got.TLS.CACert, in("certs/ca.pem")},
+		{"absolute cert", got.TLS.Cert, abs},
+		{"unset key", got.TLS.Key, ""},
+		{"netrc_file", got.Auth.NetrcFile, in(".netrc")},
+		{"schema", string(got.Assert.Schema), `"` + in("schemas/x.json") + `"`},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+
+	got, err = r.Load(context.Background(), filepath.Join(dir, "gql.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"query":"{ me { id } }"}`; got.Body != want {
+		t.Errorf("graphql body = %s, want %s", got.Body, want)
+	}
+}
+
+func TestResolvePathsKeepsImportRelativeProtos(t *testing.T) {
+	req := &types.PokeRequest{GRPC: &types.GRPCOptions{Protos: []string{"api/v1/a.proto"}, ImportPaths: []string{"protos"}}}
+	resolvePaths(req, "/work")
+	if req.GRPC.ImportPaths[0] != filepath.Join("/work", "protos") || req.GRPC.Protos[0] != "api/v1/a.proto" {
+		t.Errorf("got import paths %v and protos %v", req.GRPC.ImportPaths, req.GRPC.Protos)
+	}
+}
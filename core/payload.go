@@ -13,6 +13,8 @@ type PayloadResolver interface {
 type PayloadResolverImpl struct{}
 
 // prefill, prefill is from file, error
+// File and stdin bodies are left to be streamed when the request is sent, so
+// they are only read here when they need to go through the editor.
 func (r *PayloadResolverImpl) Resolve(data string, dataFile string, dataStdin bool, edit bool) (string, bool, error) {
 	var prefill string
 	fromFile := false
@@ -20,6 +22,9 @@ func (r *PayloadResolverImpl) Resolve(data string, dataFile string, dataStdin bo
 	switch {
 	case data != "":
 		prefill = data
+	case dataFile != "" && !edit:
+		fromFile = true
+	case dataStdin && !edit:
 	case dataFile != "":
 		bytes, err := os.ReadFile(dataFile)
 		if err != nil {
@@ -2,63 +2,105 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"google.golang.org/protobuf/reflect/protoreflect"
+
 	"poke/types"
 	"poke/util"
 )
 
 type RequestRunner interface {
-	Execute(req *types.PokeRequest) error
-	Send(req *types.PokeRequest) (*types.PokeResponse, error)
-	SendAndVerify(req *types.PokeRequest) (*types.PokeResponse, bool, error)
-	Collect(path string) error
+	Execute(ctx context.Context, req *types.PokeRequest) error
+	Send(ctx context.Context, req *types.PokeRequest) (*types.PokeResponse, error)
+	SendAndVerify(ctx context.Context, req *types.PokeRequest) (*types.PokeResponse, bool, error)
+	Collect(ctx context.Context, path string) error
 	SaveRequest(req *types.PokeRequest, saveAs string) error
 	SaveResponse(resp *types.PokeResponse) error
-	Load(path string) (*types.PokeRequest, error)
+	Load(ctx context.Context, path string) (*types.PokeRequest, error)
+	WebSocket(ctx context.Context, req *types.PokeRequest) error
 }
 
 type RequestRunnerImpl struct {
 	Tmpl *TemplateEngineImpl
 	Pyld *PayloadResolverImpl
 	Opts *types.CLIOptions
+
+	mu      sync.Mutex
+	clients map[clientConfig]*http.Client
+
+	tokenMu sync.Mutex
+	tokens  map[string]cachedToken
+
+	descMu  sync.Mutex
+	methods map[string]protoreflect.MethodDescriptor
+
+	snapMu    sync.Mutex
+	snapshots map[string]bool // snapshot files written by this run
 }
 
 func NewRequestRunner(opts *types.CLIOptions) *RequestRunnerImpl {
 	return &RequestRunnerImpl{
-		Tmpl: &TemplateEngineImpl{},
-		Pyld: &PayloadResolverImpl{},
-		Opts: opts,
+		Tmpl:    &TemplateEngineImpl{},
+		Pyld:    &PayloadResolverImpl{},
+		Opts:    opts,
+		clients: map[clientConfig]*http.Client{},
 	}
 }
 
 // Execute sends one or more requests, handling dry-run, retries, concurrency, and output.
 // For a single request, prints the response; for multiple, prints a benchmark summary.
-func (r *RequestRunnerImpl) Execute(req *types.PokeRequest) error {
+// If ctx is canceled mid-run, the summary covers the requests that finished.
+func (r *RequestRunnerImpl) Execute(ctx context.Context, req *types.PokeRequest) error {
 	if r.Opts.DryRun {
 		util.DumpRequest(req)
 		return nil
 	}
 
 	req.Workers = max(req.Workers, 1)
-	req.Workers = min(req.Workers, req.Repeat)
+	if req.Duration <= 0 {
+		req.Workers = min(req.Workers, req.Repeat)
+	}
+
+	single := req.Repeat <= 1 && req.Duration <= 0
+	if req.BodyStdin && r.replaysBody(req) {
+		if err := BufferBody(req); err != nil {
+			return err
+		}
+	}
+	if single {
+		ctx = withLiveOutput(ctx)
+	}
 
-	results, totalTime := r.dispatch(req)
+	results, totalTime := r.dispatch(ctx, req)
 
-	if req.Repeat <= 1 {
+	if single {
+		if ctx.Err() != nil && len(results) == 0 {
+			return fmt.Errorf("request interrupted")
+		}
 		if len(results) == 1 {
 			res := results[0]
 
 			_ = r.SaveResponse(res.Resp)
 			if res.Ok {
-				if r.Opts.Verbose {
+				if res.Resp.Output != "" {
+					util.PrintDownload(res.Resp)
+				} else if res.Resp.Events != nil {
+					util.Info("%s: %d events received", util.ColorStatus(res.Resp.StatusCode), len(res.Resp.Events))
+				} else if r.Opts.Verbose {
 					util.PrintResponseVerbose(res.Resp, req, res.Resp.Duration)
 				} else {
 					if res.Resp.StatusCode != 404 {
@@ -69,6 +111,15 @@ func (r *RequestRunnerImpl) Execute(req *types.PokeRequest) error {
 				}
 			}
 
+			if res.Resp != nil && res.Resp.GRPC != nil {
+				util.PrintGRPCStatus(res.Resp.GRPC)
+			}
+			if res.Ok && len(res.Resp.GraphQL) > 0 {
+				util.PrintGraphQLErrors(res.Resp.GraphQL)
+			}
+			if res.Resp != nil && res.Resp.Truncated {
+				util.Warn("Body truncated to %s of %s (--max-body)", util.FormatBytes(int64(len(res.Resp.Body))), util.FormatBytes(res.Resp.Bytes))
+			}
 			if res.Err != nil || !res.Ok {
 				util.Error("Request failed: %v", res.Err)
 			}
@@ -76,43 +127,92 @@ func (r *RequestRunnerImpl) Execute(req *types.PokeRequest) error {
 		return nil
 	}
 
+	if ctx.Err() != nil {
+		util.Warn("Interrupted, summarising %d completed requests", len(results))
+	}
+	checkRate(req, results)
+
 	var successes, failures int
 	var durations []time.Duration
+	var timings []types.Timings
+	var samples []types.BenchmarkSample
+	statusCodes := map[int]int{}
+	errorClasses := map[string]int{}
 	for _, res := range results {
+		sample := types.BenchmarkSample{Num: res.Num, Offset: res.Offset, Latency: res.Latency}
+		if res.Resp != nil {
+			statusCodes[res.Resp.StatusCode]++
+			sample.Status = res.Resp.StatusCode
+		}
 		if res.Err != nil || !res.Ok {
 			failures++
+			sample.Error = util.ClassifyError(res.Resp, res.Err)
+			errorClasses[sample.Error]++
 		} else {
 			successes++
-			durations = append(durations, res.Resp.Duration)
+			durations = append(durations, res.Latency)
+			if res.Resp.Timings != nil {
+				timings = append(timings, *res.Resp.Timings)
+			}
 		}
+		samples = append(samples, sample)
 	}
+	slices.SortFunc(samples, func(a, b types.BenchmarkSample) int { return a.Num - b.Num })
 	bench := types.BenchmarkResult{
-		Total:     req.Repeat,
+		Total:     len(results),
 		Successes: successes,
 		Failures:  failures,
 		Durations: durations,
+		Timings:   timings,
+
+		StatusCodes: statusCodes,
+		Errors:      errorClasses,
+		Samples:     samples,
 	}
 	util.PrintBenchmarkResults(bench, totalTime.Seconds(), req)
+
+	if r.Opts.BenchOut != "" {
+		report := util.NewBenchmarkReport(bench, totalTime, req)
+		if err := util.WriteBenchmarkReport(report, r.Opts.BenchOut); err != nil {
+			return fmt.Errorf("failed to write benchmark results: %w", err)
+		}
+		util.Info("Benchmark results written to: %s", r.Opts.BenchOut)
+	}
 	return nil
 }
 
+// replaysBody reports whether req's body may be sent more than once: on
+// repeats and retries, to sign it, or to answer an auth challenge.
+func (r *RequestRunnerImpl) replaysBody(req *types.PokeRequest) bool {
+	if req.Repeat > 1 || req.Duration > 0 || req.Retries > 1 || r.signingFor(req) != nil {
+		return true
+	}
+	auth, _ := r.authFor(req)
+	return auth != nil && slices.Contains([]string{"digest", "oauth2"}, strings.ToLower(auth.Type))
+}
+
 // execResult holds the outcome of a single HTTP request send & verify.
 type execResult struct {
-	Resp *types.PokeResponse
-	Ok   bool
-	Err  error
+	Resp    *types.PokeResponse
+	Ok      bool
+	Err     error
+	Num     int
+	Offset  time.Duration // when the request started, relative to the run
+	Latency time.Duration
+	Lag     time.Duration // how late a rate-mode request left its schedule
 }
 
 // sendWithRetries performs up to req.Retries attempts, with exponential backoff on failure.
-func (r *RequestRunnerImpl) sendWithRetries(req *types.PokeRequest) (*types.PokeResponse, bool, error) {
+// Backoff sleeps are cut short when ctx is canceled.
+func (r *RequestRunnerImpl) sendWithRetries(ctx context.Context, req *types.PokeRequest) (*types.PokeResponse, bool, error) {
 	base := time.Duration(req.Backoff) * time.Second
 	max := base + 10*time.Second
 	var resp *types.PokeResponse
 	var err error
 	ok := false
 	for i := range req.Retries {
-		resp, ok, err = r.SendAndVerify(req)
-		if ok {
+		resp, ok, err = r.SendAndVerify(ctx, req)
+		if ok || ctx.Err() != nil {
 			break
 		}
 		backoff := util.Backoff(base, max, i)
@@ -123,57 +223,96 @@ func (r *RequestRunnerImpl) sendWithRetries(req *types.PokeRequest) (*types.Poke
 			}
 		}
 		if i < req.Retries-1 {
-			time.Sleep(backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return resp, ok, err
+			}
 		}
 	}
 	return resp, ok, err
 }
 
-// dispatch runs req.Repeat request jobs across up to req.Workers goroutines.
-// Returns a slice of execResult and the total elapsed time.
-func (r *RequestRunnerImpl) dispatch(req *types.PokeRequest) ([]execResult, time.Duration) {
-	count := req.Repeat
-	jobs := make(chan int, count)
-	for i := 1; i <= count; i++ {
-		jobs <- i
-	}
-	close(jobs)
-	results := make(chan execResult, count)
+// dispatch runs request jobs across up to req.Workers goroutines, either
+// req.Repeat of them or as many as fit in req.Duration, paced by req.Rate.
+// Returns a slice of execResult and the total elapsed time. Canceling ctx stops
+// scheduling and aborts in-flight requests, which are left out of the results.
+func (r *RequestRunnerImpl) dispatch(ctx context.Context, req *types.PokeRequest) ([]execResult, time.Duration) {
+	jobs := make(chan job, req.Workers)
+	results := make(chan execResult, req.Workers)
 	var wg sync.WaitGroup
 	start := time.Now()
-	for range req.Workers {
+	go schedule(ctx, req, start, jobs)
+	for i := range req.Workers {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for num := range jobs {
-				resp, ok, err := r.sendWithRetries(req)
+			if req.Rate <= 0 {
+				sleepCtx(ctx, workerDelay(i, req.Workers, time.Duration(req.RampUp)))
+			}
+			for j := range jobs {
+				sent := time.Now()
+				resp, ok, err := r.sendWithRetries(ctx, req)
+				if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+					continue
+				}
+				res := execResult{Resp: resp, Ok: ok, Err: err, Num: j.num, Offset: sent.Sub(start)}
+				if !j.scheduled.IsZero() {
+					res.Latency = time.Since(j.scheduled)
+					res.Lag = sent.Sub(j.scheduled)
+				} else if resp != nil {
+					res.Latency = resp.Duration
+				}
 				if r.Opts.Verbose && resp != nil {
-					util.Info("Req %-3d: %s (%v)", num, util.ColorStatus(resp.StatusCode), resp.Duration)
+					util.Info("Req %-3d: %s (%v)", j.num, util.ColorStatus(resp.StatusCode), res.Latency)
 				}
-				results <- execResult{Resp: resp, Ok: ok, Err: err}
+				results <- res
 			}
 		}()
 	}
-	wg.Wait()
-	total := time.Since(start)
-	close(results)
-	out := make([]execResult, 0, count)
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	var progress *util.Progress
+	if !r.Opts.Verbose && (req.Repeat > 1 || req.Duration > 0) {
+		total := req.Repeat
+		if req.Duration > 0 {
+			total = 0
+		}
+		progress = util.NewProgress(total, time.Duration(req.Duration))
+		progress.Start()
+	}
+	out := make([]execResult, 0, req.Repeat)
 	for res := range results {
 		out = append(out, res)
+		if progress != nil {
+			progress.Record(res.Latency, res.Err != nil || !res.Ok)
+		}
 	}
-	return out, total
+	if progress != nil {
+		progress.Stop()
+	}
+	return out, time.Since(start)
 }
 
 // SendAndVerify sends the HTTP request and applies assertions.
-func (r *RequestRunnerImpl) SendAndVerify(req *types.PokeRequest) (*types.PokeResponse, bool, error) {
-	resp, err := r.Send(req)
+func (r *RequestRunnerImpl) SendAndVerify(ctx context.Context, req *types.PokeRequest) (*types.PokeResponse, bool, error) {
+	resp, err := r.Send(ctx, req)
 	if err != nil {
 		return nil, false, err
 	}
 	defer resp.Raw.Body.Close()
-	if req.Assert != nil {
-		ok, err := util.AssertResponse(resp, req.Assert)
-		if !ok {
+	// even without explicit assertions, gRPC and GraphQL errors fail the request
+	assert := req.Assert
+	if assert == nil {
+		assert = &types.Assertions{}
+	}
+	if ok, err := r.verify(resp, assert); !ok {
+		return resp, false, err
+	}
+	if req.Source != "" {
+		if err := r.checkSnapshot(req, resp); err != nil {
 			return resp, false, err
 		}
 	}
@@ -181,38 +320,183 @@ func (r *RequestRunnerImpl) SendAndVerify(req *types.PokeRequest) (*types.PokeRe
 }
 
 // Send constructs and sends the HTTP request, capturing timing and response.
-func (r *RequestRunnerImpl) Send(req *types.PokeRequest) (*types.PokeResponse, error) {
-	client := &http.Client{}
-	httpReq, err := http.NewRequest(req.Method, req.FullURL, bytes.NewBufferString(req.Body))
+func (r *RequestRunnerImpl) Send(ctx context.Context, req *types.PokeRequest) (*types.PokeResponse, error) {
+	if req.GRPC != nil {
+		return r.sendGRPC(ctx, req)
+	}
+	client, err := r.client(req)
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range req.Headers {
-		httpReq.Header.Set(k, strings.Join(v, ","))
+	sse := r.sseFor(req)
+	if sse != nil && sse.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(sse.Timeout))
+		defer cancel()
 	}
 	start := time.Now()
-	rawResp, err := client.Do(httpReq)
+	rawResp, httpReq, trace, err := r.do(ctx, client, req)
 	duration := time.Since(start)
 	if err != nil {
-		return nil, err
-	}
-	bodyBytes, err := util.ReadResponse(rawResp)
-	if err != nil {
-		return nil, err
+		return nil, describeTLSError(err)
 	}
-	return &types.PokeResponse{
+	resp := &types.PokeResponse{
 		StatusCode:  rawResp.StatusCode,
 		Headers:     rawResp.Header,
-		Body:        bodyBytes,
 		ContentType: rawResp.Header.Get("Content-Type"),
 		Raw:         rawResp,
-		Timestamp:   time.Now(),
 		Duration:    duration,
-	}, nil
+		Proxy:       proxyFor(client, httpReq),
+	}
+	switch {
+	case r.Opts.Output != "" && rawResp.StatusCode == http.StatusRequestedRangeNotSatisfiable && httpReq.Header.Get("Range") != "":
+		// nothing left to fetch: the partial file is already complete
+		resp.Output = r.Opts.Output
+		resp.Resumed = r.resumeOffset()
+	case r.Opts.Output != "" && rawResp.StatusCode >= 200 && rawResp.StatusCode < 300:
+		resp.Output = r.Opts.Output
+		if resp.Bytes, resp.Resumed, err = download(ctx, rawResp, r.Opts.Output); err != nil {
+			return nil, err
+		}
+	case sse != nil || isEventStream(rawResp):
+		if resp.Events, resp.Bytes, err = r.readEvents(ctx, client, req, rawResp, sse); err != nil {
+			return nil, err
+		}
+	default:
+		if resp.Body, resp.Bytes, resp.Truncated, err = readBody(rawResp, r.Opts.MaxBody); err != nil {
+			return nil, err
+		}
+		if req.GraphQL != nil {
+			resp.GraphQL = graphQLErrors(resp.Body)
+		}
+	}
+	resp.Timestamp = time.Now()
+	resp.Timings = trace.timings(resp.Timestamp)
+	return resp, nil
+}
+
+// do performs the HTTP exchange for req, including any extra round trip the
+// auth scheme needs, and returns the final response and the request behind it.
+func (r *RequestRunnerImpl) do(ctx context.Context, client *http.Client, req *types.PokeRequest) (*http.Response, *http.Request, *phaseTrace, error) {
+	auth, err := r.authFor(req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sign := r.signingFor(req)
+	httpReq, trace, err := r.newHTTPRequest(ctx, req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := r.applyAuth(ctx, client, httpReq, auth, false); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := r.swapAuthToken(ctx, client, httpReq, req, false); err != nil {
+		return nil, nil, nil, err
+	}
+	rawResp, err := roundTrip(client, httpReq, sign)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Digest answers the server's 401 challenge; OAuth2 retries a 401 once
+	// with a freshly fetched token.
+	var reauth func(*http.Request) error
+	if challenge := digestChallenge(rawResp, auth); challenge != "" {
+		reauth = func(httpReq *http.Request) error {
+			authz, err := digestAuthorization(challenge, auth, httpReq)
+			if err != nil {
+				return err
+			}
+			httpReq.Header.Set("Authorization", authz)
+			return nil
+		}
+	} else if oauth2Rejected(rawResp, auth, req) {
+		reauth = func(httpReq *http.Request) error {
+			if req.AuthToken != "" {
+				return r.swapAuthToken(ctx, client, httpReq, req, true)
+			}
+			return r.applyAuth(ctx, client, httpReq, auth, true)
+		}
+	}
+	if reauth == nil {
+		return rawResp, httpReq, trace, nil
+	}
+
+	io.Copy(io.Discard, rawResp.Body)
+	rawResp.Body.Close()
+	httpReq, trace, err = r.newHTTPRequest(ctx, req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := reauth(httpReq); err != nil {
+		return nil, nil, nil, err
+	}
+	rawResp, err = roundTrip(client, httpReq, sign)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return rawResp, httpReq, trace, nil
+}
+
+// roundTrip signs httpReq, if the request asks for it, and sends it.
+func roundTrip(client *http.Client, httpReq *http.Request, sign *types.Signing) (*http.Response, error) {
+	if err := signRequest(httpReq, sign); err != nil {
+		return nil, err
+	}
+	return client.Do(httpReq)
+}
+
+// newHTTPRequest builds a fresh *http.Request for req with a phase trace
+// attached. It is called again for every round trip so the body is never reused.
+func (r *RequestRunnerImpl) newHTTPRequest(ctx context.Context, req *types.PokeRequest) (*http.Request, *phaseTrace, error) {
+	trace := newPhaseTrace()
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.FullURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, size, err := openBody(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if size == 0 {
+		body.Close()
+	} else {
+		if liveOutput(ctx) && streamsBody(req) {
+			progress := util.NewTransferProgress("Uploading", size)
+			progress.Start()
+			body = progress.Reader(body)
+		}
+		// a negative length is sent chunked
+		httpReq.Body = body
+		httpReq.ContentLength = size
+		if !req.BodyStdin {
+			httpReq.GetBody = func() (io.ReadCloser, error) {
+				body, _, err := openBody(req)
+				return body, err
+			}
+		}
+	}
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, strings.Join(v, ","))
+	}
+	if offset := r.resumeOffset(); offset > 0 {
+		httpReq.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	if sse := r.sseFor(req); sse != nil {
+		if httpReq.Header.Get("Accept") == "" {
+			httpReq.Header.Set("Accept", "text/event-stream")
+		}
+		if sse.LastEventID != "" && httpReq.Header.Get("Last-Event-ID") == "" {
+			httpReq.Header.Set("Last-Event-ID", sse.LastEventID)
+		}
+	}
+	return httpReq, trace, nil
 }
 
 // SaveRequest writes a PokeRequest to path, clearing Body if BodyFile is set.
 func (r *RequestRunnerImpl) SaveRequest(req *types.PokeRequest, path string) error {
+	req = withoutSecrets(req)
 	out, err := json.MarshalIndent(req, "", "  ")
 	if err != nil {
 		return err
@@ -226,6 +510,40 @@ func (r *RequestRunnerImpl) SaveRequest(req *types.PokeRequest, path string) err
 	return os.WriteFile(path, out, 0644)
 }
 
+// withoutSecrets returns a copy of req whose credentials are replaced by
+// {{ env.VAR }} placeholders, so secrets never land on disk in clear text.
+func withoutSecrets(req *types.PokeRequest) *types.PokeRequest {
+	saved := *req
+	placeholder := func(value *string, env string) {
+		if *value != "" && !strings.Contains(*value, "{{") {
+			*value = "{{ env." + env + " }}"
+			util.Warn("Saved request reads its secret from $%s", env)
+		}
+	}
+	if req.Auth != nil {
+		auth := *req.Auth
+		placeholder(&auth.Password, "POKE_PASSWORD")
+		placeholder(&auth.Token, "POKE_TOKEN")
+		saved.Auth = &auth
+	}
+	if req.Sign != nil {
+		sign := *req.Sign
+		placeholder(&sign.Secret, "POKE_HMAC_SECRET")
+		saved.Sign = &sign
+	}
+	if u, err := url.Parse(req.Proxy); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			placeholder(&password, "POKE_PROXY_PASSWORD")
+			// built by hand: url.URL would escape the placeholder's braces
+			user := url.User(u.User.Username()).String()
+			u.User = nil
+			scheme, rest, _ := strings.Cut(u.String(), "://")
+			saved.Proxy = scheme + "://" + user + ":" + password + "@" + rest
+		}
+	}
+	return &saved
+}
+
 // SaveResponse writes the latest response to ~/.poke/tmp_poke_latest.json
 func (r *RequestRunnerImpl) SaveResponse(resp *types.PokeResponse) error {
 	if resp == nil {
@@ -242,9 +560,9 @@ func (r *RequestRunnerImpl) SaveResponse(resp *types.PokeResponse) error {
 	return os.WriteFile(filepath.Join(home, ".poke", "tmp_poke_latest.json"), out, 0644)
 }
 
-// Collect loads and sends saved request(s) from a file or directory.
 // Collect loads and sends one or more saved requests from a file or directory.
-func (r *RequestRunnerImpl) Collect(path string) error {
+// Canceling ctx stops the run between requests.
+func (r *RequestRunnerImpl) Collect(ctx context.Context, path string) error {
 	paths, err := walkPath(path)
 	if err != nil {
 		return fmt.Errorf("could not resolve file/directory: %w", err)
@@ -253,29 +571,36 @@ func (r *RequestRunnerImpl) Collect(path string) error {
 		return fmt.Errorf("no .json files found for '%s'", path)
 	}
 	for i, p := range paths {
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted after %d of %d requests", i, len(paths))
+		}
 		fmt.Println(strings.Repeat("-", 40))
 		fmt.Printf("Request %d/%d: %s\n", i+1, len(paths), p)
 		fmt.Println(strings.Repeat("-", 40))
-		req, err := r.Load(p)
+		req, err := r.Load(ctx, p)
 		if err != nil {
 			fmt.Printf("File '%s' is not a valid request: %v\n", p, err)
 			continue
 		}
-		body, _, err := r.Pyld.Resolve(string(req.Body), req.BodyFile, false, false)
-		if err != nil {
-			fmt.Printf("Failed to resolve body for '%s': %v\n", p, err)
-			continue
+		if req.WebSocket != nil {
+			err = r.WebSocket(ctx, req)
+		} else {
+			err = r.Execute(ctx, req)
 		}
-		req.Body = body
-		if err := r.Execute(req); err != nil {
+		if err != nil {
 			fmt.Printf("Request failed: %v\n", err)
 		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted during request %d of %d, %d completed", i+1, len(paths), i)
+		}
 	}
 	return nil
 }
 
 // Load reads and renders a .json request template into a PokeRequest.
-func (r *RequestRunnerImpl) Load(fpath string) (*types.PokeRequest, error) {
+// Templates that use {{ auth.token }} are rendered a second time once the
+// request's OAuth2 token has been fetched.
+func (r *RequestRunnerImpl) Load(ctx context.Context, fpath string) (*types.PokeRequest, error) {
 	data, err := os.ReadFile(fpath)
 	if err != nil {
 		return nil, err
@@ -284,13 +609,22 @@ func (r *RequestRunnerImpl) Load(fpath string) (*types.PokeRequest, error) {
 	if err != nil {
 		return nil, err
 	}
-	if req.BodyFile != "" && req.Body == "" {
-		content, err := os.ReadFile(req.BodyFile)
+	if req.Auth != nil && req.Auth.OAuth2 != nil && bytes.Contains(data, []byte("auth.token")) {
+		client, err := r.client(req)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read body file: %w", err)
+			return nil, err
 		}
-		req.Body = string(content)
+		token, err := r.oauth2Token(ctx, client, req.Auth.OAuth2, false)
+		if err != nil {
+			return nil, err
+		}
+		r.Tmpl.SetAuthToken(token)
+		if req, err = r.Tmpl.RenderRequest(data); err != nil {
+			return nil, err
+		}
+		req.AuthToken = token
 	}
+	resolvePaths(req, filepath.Dir(fpath))
 	scheme := req.Scheme
 	if scheme == "" {
 		scheme = "http"
@@ -311,10 +645,17 @@ func (r *RequestRunnerImpl) Load(fpath string) (*types.PokeRequest, error) {
 		queryStr = "?" + strings.Join(parts, "&")
 	}
 	req.FullURL = fmt.Sprintf("%s://%s%s%s", scheme, host, path, queryStr)
+	if req.Headers == nil {
+		req.Headers = map[string][]string{}
+	}
+	if err := ApplyGraphQL(req); err != nil {
+		return nil, err
+	}
 	req.ContentType = util.DetectContentType(req)
 	if req.ContentType != "" {
 		req.Headers["Content-Type"] = []string{req.ContentType}
 	}
+	req.Source = fpath
 	return req, nil
 }
 
@@ -330,7 +671,8 @@ func walkPath(path string) ([]string, error) {
 	var paths []string
 	if info.IsDir() {
 		filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
-			if err == nil && !fi.IsDir() && strings.HasSuffix(fi.Name(), ".json") {
+			// .snap.json files are recorded responses, not requests
+			if err == nil && !fi.IsDir() && strings.HasSuffix(fi.Name(), ".json") && !strings.HasSuffix(fi.Name(), ".snap.json") {
 				paths = append(paths, p)
 			}
 			return nil
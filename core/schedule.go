@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"poke/types"
+	"poke/util"
+)
+
+// job is one unit of work handed to a dispatch worker. In rate mode scheduled
+// is when the request should have been sent; latency is measured from it so
+// that queueing behind slow responses is not hidden (coordinated omission).
+type job struct {
+	num       int
+	scheduled time.Time
+}
+
+// schedule feeds jobs until req.Repeat is reached or, when req.Duration is
+// set, until the duration elapses. With req.Rate it paces jobs as an open
+// model; otherwise jobs are handed out as fast as workers take them.
+// It stops early once ctx is canceled.
+func schedule(ctx context.Context, req *types.PokeRequest, start time.Time, jobs chan<- job) {
+	defer close(jobs)
+
+	var deadline time.Time
+	if req.Duration > 0 {
+		deadline = start.Add(time.Duration(req.Duration))
+	}
+
+	for num := 1; ; num++ {
+		if deadline.IsZero() && num > req.Repeat {
+			return
+		}
+		j := job{num: num}
+		if req.Rate > 0 {
+			j.scheduled = start.Add(arrivalOffset(num-1, req.Rate, time.Duration(req.RampUp)))
+			if !deadline.IsZero() && !j.scheduled.Before(deadline) {
+				return
+			}
+			if !sleepCtx(ctx, time.Until(j.scheduled)) {
+				return
+			}
+		} else if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return
+		}
+		select {
+		case jobs <- j:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkRate warns when rate-mode requests left later than scheduled because
+// every worker was busy, suggesting a pool of rate × mean response time.
+func checkRate(req *types.PokeRequest, results []execResult) {
+	if req.Rate <= 0 {
+		return
+	}
+	interval := seconds(1 / req.Rate)
+	var late, served int
+	var worst, service time.Duration
+	for _, res := range results {
+		if res.Lag > interval {
+			late++
+		}
+		worst = max(worst, res.Lag)
+		if res.Resp != nil {
+			served++
+			service += res.Resp.Duration
+		}
+	}
+	if late == 0 {
+		return
+	}
+	msg := fmt.Sprintf("%d of %d requests were sent late (up to %v behind schedule), so the target rate of %g/s was not met",
+		late, len(results), worst.Round(time.Millisecond), req.Rate)
+	if served > 0 {
+		if need := int(math.Ceil(req.Rate * (service / time.Duration(served)).Seconds())); need > req.Workers {
+			msg += fmt.Sprintf("; try --workers %d", need)
+		}
+	}
+	util.Warn("%s", msg)
+}
+
+// arrivalOffset returns when the k-th (0-based) request is due for a constant
+// rate per second, optionally ramped up linearly from zero over rampUp.
+func arrivalOffset(k int, rate float64, rampUp time.Duration) time.Duration {
+	n := float64(k)
+	ramp := rampUp.Seconds()
+	if ramp <= 0 {
+		return seconds(n / rate)
+	}
+	// During the ramp the request count is rate*t²/(2*ramp); solve for t.
+	rampCount := rate * ramp / 2
+	if n < rampCount {
+		return seconds(math.Sqrt(2 * ramp * n / rate))
+	}
+	return seconds(ramp + (n-rampCount)/rate)
+}
+
+// workerDelay staggers worker start times across rampUp in the closed model.
+func workerDelay(worker, workers int, rampUp time.Duration) time.Duration {
+	if rampUp <= 0 || workers <= 1 {
+		return 0
+	}
+	return rampUp * time.Duration(worker) / time.Duration(workers)
+}
+
+// sleepCtx sleeps for d, returning false if ctx was canceled first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,313 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"poke/types"
+)
+
+// SigningFromOptions builds the signing settings given on the command line,
+// or nil if none were given.
+func SigningFromOptions(opts *types.CLIOptions) *types.Signing {
+	if opts.Sign == "" {
+		return nil
+	}
+	return &types.Signing{
+		Type:      opts.Sign,
+		Region:    opts.AWSRegion,
+		Service:   opts.AWSService,
+		Secret:    opts.HMACSecret,
+		KeyID:     opts.HMACKeyID,
+		Algorithm: opts.HMACAlgorithm,
+		Header:    opts.HMACHeader,
+		Layout:    strings.ReplaceAll(opts.HMACLayout, `\n`, "\n"),
+	}
+}
+
+// signingFor returns the signing settings for req, falling back to the CLI
+// options for saved requests that do not declare their own.
+func (r *RequestRunnerImpl) signingFor(req *types.PokeRequest) *types.Signing {
+	if req.Sign != nil {
+		return req.Sign
+	}
+	return SigningFromOptions(r.Opts)
+}
+
+// signRequest signs the fully built request in place. It runs after auth and
+// headers are applied, immediately before the request is sent.
+func signRequest(httpReq *http.Request, sign *types.Signing) error {
+	if sign == nil {
+		return nil
+	}
+	body, err := requestBody(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to read body for signing: %w", err)
+	}
+	now := time.Now().UTC()
+	switch strings.ToLower(sign.Type) {
+	case "sigv4":
+		creds, err := awsCredentialsFromEnv()
+		if err != nil {
+			return err
+		}
+		return signSigV4(httpReq, sign, body, creds, now)
+	case "hmac":
+		return signHMAC(httpReq, sign, body, now)
+	}
+	return fmt.Errorf("unknown signing type %q (use sigv4 or hmac)", sign.Type)
+}
+
+// requestBody returns a copy of the request body without consuming it.
+func requestBody(httpReq *http.Request) ([]byte, error) {
+	if httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return nil, nil
+	}
+	if httpReq.GetBody == nil {
+		return nil, fmt.Errorf("body cannot be re-read")
+	}
+	rc, err := httpReq.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func awsCredentialsFromEnv() (awsCredentials, error) {
+	creds := awsCredentials{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return creds, fmt.Errorf("sigv4 signing requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return creds, nil
+}
+
+// signSigV4 adds an AWS Signature Version 4 Authorization header.
+func signSigV4(httpReq *http.Request, sign *types.Signing, body []byte, creds awsCredentials, now time.Time) error {
+	region := sign.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" || sign.Service == "" {
+		return fmt.Errorf("sigv4 signing requires a region and a service")
+	}
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	httpReq.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		httpReq.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+	if sign.Service == "s3" {
+		httpReq.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers := map[string]string{"host": httpReq.Host}
+	if headers["host"] == "" {
+		headers["host"] = httpReq.URL.Host
+	}
+	for k, vs := range httpReq.Header {
+		trimmed := make([]string, len(vs))
+		for i, v := range vs {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[strings.ToLower(k)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// S3 paths are encoded once; every other service encodes them twice.
+	path := httpReq.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if sign.Service != "s3" {
+		segments := strings.Split(path, "/")
+		for i, s := range segments {
+			segments[i] = awsEscape(s)
+		}
+		path = strings.Join(segments, "/")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		httpReq.Method,
+		path,
+		canonicalQuery(httpReq),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, sign.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSum(sha256.New, []byte("AWS4"+creds.secretKey), date)
+	key = hmacSum(sha256.New, key, region)
+	key = hmacSum(sha256.New, key, sign.Service)
+	key = hmacSum(sha256.New, key, "aws4_request")
+	signature := hex.EncodeToString(hmacSum(sha256.New, key, stringToSign))
+
+	httpReq.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+	return nil
+}
+
+// canonicalQuery sorts and re-encodes the query string the way SigV4 expects.
+func canonicalQuery(httpReq *http.Request) string {
+	var pairs []string
+	for k, vs := range httpReq.URL.Query() {
+		for _, v := range vs {
+			pairs = append(pairs, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything but RFC 3986 unreserved characters.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// defaultHMACLayout is the string signed when a request gives no layout.
+const defaultHMACLayout = "{method}\n{path}\n{timestamp}\n{body_sha256}"
+
+var hmacPlaceholder = regexp.MustCompile(`\{([a-z_0-9]+)(?::([^}]+))?\}`)
+
+// signHMAC signs a canonical string built from sign.Layout and writes the
+// signature to sign.Header, formatted by sign.Format.
+//
+// Layout placeholders: {method}, {path}, {query}, {host}, {body},
+// {body_sha256}, {timestamp} (unix seconds), {date} (RFC 3339) and
+// {header:Name}. Format may also use {signature} and {key_id}.
+func signHMAC(httpReq *http.Request, sign *types.Signing, body []byte, now time.Time) error {
+	if sign.Secret == "" {
+		return fmt.Errorf("hmac signing requires a secret")
+	}
+	var newHash func() hash.Hash
+	switch strings.ToLower(strings.ReplaceAll(sign.Algorithm, "-", "")) {
+	case "", "sha256", "hmacsha256":
+		newHash = sha256.New
+	case "sha512", "hmacsha512":
+		newHash = sha512.New
+	case "sha1", "hmacsha1":
+		newHash = sha1.New
+	default:
+		return fmt.Errorf("unsupported hmac algorithm %q", sign.Algorithm)
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	if sign.TimestampHeader != "" {
+		httpReq.Header.Set(sign.TimestampHeader, timestamp)
+	}
+
+	layout := sign.Layout
+	if layout == "" {
+		layout = defaultHMACLayout
+	}
+	values := map[string]string{
+		"method":      httpReq.Method,
+		"path":        httpReq.URL.EscapedPath(),
+		"query":       httpReq.URL.RawQuery,
+		"host":        httpReq.URL.Host,
+		"body":        string(body),
+		"body_sha256": sha256Hex(body),
+		"timestamp":   timestamp,
+		"date":        now.Format(time.RFC3339),
+		"key_id":      sign.KeyID,
+	}
+	expand := func(tmpl string) string {
+		return hmacPlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+			parts := hmacPlaceholder.FindStringSubmatch(m)
+			if parts[1] == "header" {
+				return httpReq.Header.Get(parts[2])
+			}
+			if v, ok := values[parts[1]]; ok {
+				return v
+			}
+			return m
+		})
+	}
+
+	sum := hmacSum(newHash, []byte(sign.Secret), expand(layout))
+	if strings.EqualFold(sign.Encoding, "base64") {
+		values["signature"] = base64.StdEncoding.EncodeToString(sum)
+	} else {
+		values["signature"] = hex.EncodeToString(sum)
+	}
+
+	header := sign.Header
+	if header == "" {
+		header = "X-Signature"
+	}
+	format := sign.Format
+	if format == "" {
+		format = "{signature}"
+	}
+	httpReq.Header.Set(header, expand(format))
+	return nil
+}
+
+func hmacSum(newHash func() hash.Hash, key []byte, data string) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
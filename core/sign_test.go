@@ -0,0 +1,103 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"poke/types"
+)
+
+// The vectors come from the AWS SigV4 test suite (get-vanilla and friends).
+func TestSignSigV4(t *testing.T) {
+	creds := awsCredentials{accessKey: "AKIDEXAMPLE", secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	sign := &types.Signing{Type: "sigv4", Region: "us-east-1", Service: "service"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	const credential = "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature="
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		want   string
+	}{
+		{"get-vanilla", http.MethodGet, "https://example.amazonaws.com/",
+			"5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", http.MethodGet, "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			"b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"post-vanilla", http.MethodPost, "https://example.amazonaws.com/",
+			"5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := signSigV4(req, sign, nil, creds, now); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q", got)
+			}
+			if got := req.Header.Get("Authorization"); got != credential+tt.want {
+				t.Errorf("Authorization =\n  %s\nwant\n  %s", got, credential+tt.want)
+			}
+		})
+	}
+}
+
+func TestSignHMAC(t *testing.T) {
+	const rfc = "what do ya want for nothing?" // RFC 4231 test case 2, key "Jefe"
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		sign       types.Signing
+		body       string
+		wantHeader string
+		want       string
+		wantErr    string
+	}{
+		{name: "sha256 hex", sign: types.Signing{Secret: "Jefe", Layout: "{body}"}, body: rfc,
+			wantHeader: "X-Signature", want: "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{name: "sha512", sign: types.Signing{Secret: "Jefe", Layout: "{body}", Algorithm: "HMAC-SHA512"}, body: rfc,
+			wantHeader: "X-Signature", want: "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737"},
+		{name: "sha1", sign: types.Signing{Secret: "Jefe", Layout: "{body}", Algorithm: "sha1"}, body: rfc,
+			wantHeader: "X-Signature", want: "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79"},
+		{name: "base64 with format", sign: types.Signing{Secret: "Jefe", Layout: "{body}", Encoding: "base64", KeyID: "key-1",
+			Header: "Authorization", Format: "HMAC {key_id}:{signature}"}, body: rfc,
+			wantHeader: "Authorization", want: "HMAC key-1:W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM="},
+		{name: "default layout", sign: types.Signing{Secret: "k"}, body: `{"a":1}`,
+			wantHeader: "X-Signature", want: "f05ef3e398344cd90702e83a6d4221e94ef97c8ee07a4ee09e95b2927cdd27a2"},
+		{name: "placeholders", body: `{"a":1}`, sign: types.Signing{Secret: "k",
+			Layout: "{method} {path}?{query} {host} {date} {header:X-Request-Id} {header:X-Missing} {nope}"},
+			wantHeader: "X-Signature", want: "47eb8e7c6270436d103132d14af9dd424aeeaa674975c13331c98cb5e9b83e2c"},
+		{name: "timestamp header", sign: types.Signing{Secret: "k", Layout: "{body}", TimestampHeader: "X-Timestamp"},
+			wantHeader: "X-Timestamp", want: "1440938160"},
+		{name: "no secret", sign: types.Signing{}, wantErr: "requires a secret"},
+		{name: "unknown algorithm", sign: types.Signing{Secret: "k", Algorithm: "md5"}, wantErr: "unsupported hmac algorithm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "https://api.example.com/v1/items%20x?b=2&a=1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-Request-Id", "abc-123")
+			err = signHMAC(req, &tt.sign, []byte(tt.body), now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get(tt.wantHeader); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.wantHeader, got, tt.want)
+			}
+		})
+	}
+}
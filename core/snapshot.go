@@ -0,0 +1,44 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"poke/types"
+	"poke/util"
+)
+
+// checkSnapshot compares resp with the .snap.json file next to the request
+// it was loaded from, or records it there with --update-snapshots. Requests
+// without a snapshot file are only checked when they have a snapshot block.
+func (r *RequestRunnerImpl) checkSnapshot(req *types.PokeRequest, resp *types.PokeResponse) error {
+	path := util.SnapshotPath(req.Source)
+	if r.Opts.UpdateSnapshots {
+		r.snapMu.Lock()
+		defer r.snapMu.Unlock()
+		if r.snapshots[path] {
+			return nil // repeated requests record the first response only
+		}
+		if err := util.WriteSnapshot(path, resp); err != nil {
+			return err
+		}
+		if r.snapshots == nil {
+			r.snapshots = map[string]bool{}
+		}
+		r.snapshots[path] = true
+		util.Info("Updated snapshot %s", path)
+		return nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if req.Snapshot == nil && os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("no snapshot %s, record one with --update-snapshots", path)
+	}
+	var ignore []string
+	if req.Snapshot != nil {
+		ignore = req.Snapshot.Ignore
+	}
+	return util.CompareSnapshot(path, resp, ignore)
+}
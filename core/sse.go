@@ -0,0 +1,173 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"poke/types"
+	"poke/util"
+)
+
+// SSEFromOptions builds SSE options from the --sse flags, or nil when none are set.
+func SSEFromOptions(opts *types.CLIOptions) *types.SSEOptions {
+	if !opts.SSE && opts.MaxEvents == 0 && opts.SSETimeout == 0 && opts.SSEReconnect == 0 {
+		return nil
+	}
+	return &types.SSEOptions{
+		MaxEvents: opts.MaxEvents,
+		Timeout:   types.Duration(opts.SSETimeout),
+		Reconnect: opts.SSEReconnect,
+	}
+}
+
+// sseFor returns the SSE options for req, preferring the request's own block.
+func (r *RequestRunnerImpl) sseFor(req *types.PokeRequest) *types.SSEOptions {
+	if req.SSE != nil {
+		return req.SSE
+	}
+	return SSEFromOptions(r.Opts)
+}
+
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// readEvents consumes an event stream until the server ends it, MaxEvents
+// arrive or ctx is done, reconnecting with Last-Event-ID while reconnects are
+// left. It returns the events and the number of body bytes read.
+func (r *RequestRunnerImpl) readEvents(ctx context.Context, client *http.Client, req *types.PokeRequest, rawResp *http.Response, sse *types.SSEOptions) ([]types.StreamEvent, int64, error) {
+	if sse == nil {
+		sse = &types.SSEOptions{}
+	}
+	live := liveOutput(ctx)
+	events := []types.StreamEvent{}
+	lastID := sse.LastEventID
+	retry := time.Second
+	var total int64
+	for attempt := 0; ; attempt++ {
+		n, err := parseEvents(rawResp.Body, func(ev types.StreamEvent) bool {
+			events = append(events, ev)
+			if ev.ID != "" {
+				lastID = ev.ID
+			}
+			if ev.Retry > 0 {
+				retry = time.Duration(ev.Retry) * time.Millisecond
+			}
+			if live {
+				util.PrintEvent(ev)
+			}
+			return sse.MaxEvents <= 0 || len(events) < sse.MaxEvents
+		})
+		rawResp.Body.Close()
+		total += n
+
+		// hitting the time limit or Ctrl-C ends the stream, it is not a failure
+		if ctx.Err() != nil || (sse.MaxEvents > 0 && len(events) >= sse.MaxEvents) {
+			return events, total, nil
+		}
+		if attempt >= sse.Reconnect {
+			if err != nil {
+				return events, total, fmt.Errorf("event stream failed: %w", err)
+			}
+			return events, total, nil
+		}
+
+		if !sleepCtx(ctx, retry) {
+			return events, total, nil
+		}
+		if live {
+			util.Info("Reconnecting (%d/%d), last event id %q", attempt+1, sse.Reconnect, lastID)
+		}
+		next := *req
+		next.Headers = maps.Clone(req.Headers)
+		if lastID != "" {
+			next.Headers["Last-Event-ID"] = []string{lastID}
+		}
+		rawResp, _, _, err = r.do(ctx, client, &next)
+		if err != nil {
+			if ctx.Err() != nil {
+				return events, total, nil
+			}
+			return events, total, fmt.Errorf("failed to reconnect event stream: %w", err)
+		}
+		if rawResp.StatusCode != http.StatusOK || !isEventStream(rawResp) {
+			rawResp.Body.Close()
+			return events, total, fmt.Errorf("reconnect returned %d %s", rawResp.StatusCode, rawResp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+// parseEvents reads text/event-stream framing from body, calling emit for
+// each complete event until emit returns false or the stream ends. A
+// partial event at the end of the stream is dropped, as the spec requires.
+func parseEvents(body io.Reader, emit func(types.StreamEvent) bool) (int64, error) {
+	counter := &countingReader{r: body}
+	br := bufio.NewReader(counter)
+	var ev types.StreamEvent
+	var data []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return counter.n, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if len(data) > 0 {
+				ev.Data = strings.Join(data, "\n")
+				if ev.Event == "" {
+					ev.Event = "message"
+				}
+				ev.Received = time.Now()
+				if !emit(ev) {
+					return counter.n, nil
+				}
+			}
+			ev, data = types.StreamEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment or keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				ev.ID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				ev.Retry = ms
+			}
+		}
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"poke/types"
+)
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		limit  int // stop after this many events, 0 for all
+		want   []types.StreamEvent
+	}{
+		{
+			name:   "default type",
+			stream: "data: hello\n\n",
+			want:   []types.StreamEvent{{Event: "message", Data: "hello"}},
+		},
+		{
+			name:   "fields and multi-line data",
+			stream: "event: update\nid: 7\nretry: 2500\ndata: {\"a\":\ndata: 1}\n\n",
+			want:   []types.StreamEvent{{Event: "update", ID: "7", Retry: 2500, Data: "{\"a\":\n1}"}},
+		},
+		{
+			name:   "crlf, comments and no space after colon",
+			stream: ": keep-alive\r\ndata:x\r\n\r\n",
+			want:   []types.StreamEvent{{Event: "message", Data: "x"}},
+		},
+		{
+			name:   "blocks without data are not dispatched",
+			stream: "event: ping\n\ndata: a\n\n",
+			want:   []types.StreamEvent{{Event: "message", Data: "a"}},
+		},
+		{
+			name:   "invalid retry and id with NUL are ignored",
+			stream: "retry: soon\nid: a\x00b\ndata: a\n\n",
+			want:   []types.StreamEvent{{Event: "message", Data: "a"}},
+		},
+		{
+			name:   "partial event at the end is dropped",
+			stream: "data: a\n\ndata: b\n",
+			want:   []types.StreamEvent{{Event: "message", Data: "a"}},
+		},
+		{
+			name:   "emit can stop the stream",
+			stream: "data: a\n\ndata: b\n\ndata: c\n\n",
+			limit:  2,
+			want:   []types.StreamEvent{{Event: "message", Data: "a"}, {Event: "message", Data: "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []types.StreamEvent
+			n, err := parseEvents(strings.NewReader(tt.stream), func(ev types.StreamEvent) bool {
+				if ev.Received.IsZero() {
+					t.Error("event has no received time")
+				}
+				got = append(got, types.StreamEvent{ID: ev.ID, Event: ev.Event, Data: ev.Data, Retry: ev.Retry})
+				return tt.limit == 0 || len(got) < tt.limit
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.limit == 0 && n != int64(len(tt.stream)) {
+				t.Errorf("read %d bytes, want %d", n, len(tt.stream))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events %+v, want %+v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -17,11 +17,13 @@ import (
 type TemplateContext struct {
 	Env     map[string]string
 	History map[string]any
+	Auth    map[string]string
 }
 
 type TemplateEngine interface {
 	LoadEnv()
 	LoadHistory() error
+	SetAuthToken(token string)
 	RenderRequest(path string) (*types.PokeRequest, error)
 }
 
@@ -73,6 +75,29 @@ func (t *TemplateEngineImpl) LoadHistory() error {
 	return nil
 }
 
+// SetAuthToken exposes an access token to templates as {{ auth.token }}.
+func (t *TemplateEngineImpl) SetAuthToken(token string) {
+	t.ctx.Auth = map[string]string{"token": token}
+}
+
+// funcMap exposes the template context as functions for property-style lookup.
+func (t *TemplateEngineImpl) funcMap() template.FuncMap {
+	return template.FuncMap{
+		// env: returns the environment map for property-style lookup, e.g., {{ env.TOKEN }}
+		"env": func() map[string]string {
+			return t.ctx.Env
+		},
+		// history: returns the history map for property-style lookup, e.g., {{ history.body }} or nested {{ history.body.data }}
+		"history": func() any {
+			return t.ctx.History
+		},
+		// auth: returns values from the request's auth flow, e.g., {{ auth.token }}
+		"auth": func() map[string]string {
+			return t.ctx.Auth
+		},
+	}
+}
+
 func (t *TemplateEngineImpl) RenderRequest(data []byte) (*types.PokeRequest, error) {
 	t.LoadEnv()
 	if err := t.LoadHistory(); err != nil {
@@ -81,16 +106,7 @@ func (t *TemplateEngineImpl) RenderRequest(data []byte) (*types.PokeRequest, err
 
 	tmpl, err := template.New("poke").
 		Funcs(sprig.TxtFuncMap()).
-		Funcs(template.FuncMap{
-			// env: returns the environment map for property-style lookup, e.g., {{ env.TOKEN }}
-			"env": func() map[string]string {
-				return t.ctx.Env
-			},
-			// history: returns the history map for property-style lookup, e.g., {{ history.body }} or nested {{ history.body.data }}
-			"history": func() any {
-				return t.ctx.History
-			},
-		}).
+		Funcs(t.funcMap()).
 		Parse(string(data))
 	if err != nil {
 		return nil, fmt.Errorf("template parse: %w", err)
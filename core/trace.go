@@ -0,0 +1,77 @@
+package core
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"poke/types"
+)
+
+// phaseTrace records httptrace callbacks for a single request. Callbacks can
+// fire from the transport's dial goroutines, so access is locked.
+type phaseTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+func newPhaseTrace() *phaseTrace {
+	return &phaseTrace{start: time.Now()}
+}
+
+func (p *phaseTrace) mark(t *time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+func (p *phaseTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { p.mark(&p.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { p.mark(&p.dnsDone) },
+		ConnectStart:      func(string, string) { p.mark(&p.connectStart) },
+		ConnectDone:       func(string, string, error) { p.mark(&p.connectDone) },
+		TLSHandshakeStart: func() { p.mark(&p.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { p.mark(&p.tlsDone) },
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			// retried writes on a stale keep-alive connection overwrite the first
+			p.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() { p.mark(&p.firstByte) },
+	}
+}
+
+// timings converts the recorded marks into phase durations, with done being
+// the moment the body finished reading.
+func (p *phaseTrace) timings(done time.Time) *types.Timings {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &types.Timings{
+		DNS:      between(p.dnsStart, p.dnsDone),
+		Connect:  between(p.connectStart, p.connectDone),
+		TLS:      between(p.tlsStart, p.tlsDone),
+		TTFB:     between(p.wroteRequest, p.firstByte),
+		Transfer: between(p.firstByte, done),
+		Total:    between(p.start, done),
+	}
+}
+
+func between(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
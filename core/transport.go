@@ -0,0 +1,283 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"poke/types"
+)
+
+// clientConfig holds the transport settings that decide which shared client
+// a request is sent with. Requests with equal configs share a connection pool.
+type clientConfig struct {
+	connectTimeout        time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+	timeout               time.Duration
+	noKeepAlive           bool
+	maxIdleConnsPerHost   int
+
+	caCert     string
+	cert       string
+	key        string
+	insecure   bool
+	serverName string
+
+	proxy   string
+	noProxy string
+
+	http2Only bool // gRPC: HTTP/2 over TLS, or h2c with prior knowledge for http://
+}
+
+// clientConfigFor merges the CLI options with any per-request overrides.
+func (r *RequestRunnerImpl) clientConfigFor(req *types.PokeRequest) clientConfig {
+	cfg := clientConfig{
+		connectTimeout:        r.Opts.ConnectTimeout,
+		tlsHandshakeTimeout:   r.Opts.TLSHandshakeTimeout,
+		responseHeaderTimeout: r.Opts.ResponseHeaderTimeout,
+		timeout:               r.Opts.Timeout,
+		noKeepAlive:           r.Opts.NoKeepAlive || req.NoKeepAlive,
+		maxIdleConnsPerHost:   max(req.Workers, http.DefaultMaxIdleConnsPerHost),
+		caCert:                r.Opts.CACert,
+		cert:                  r.Opts.Cert,
+		key:                   r.Opts.Key,
+		insecure:              r.Opts.Insecure,
+		serverName:            r.Opts.ServerName,
+		proxy:                 r.Opts.Proxy,
+		noProxy:               r.Opts.NoProxy,
+		http2Only:             req.GRPC != nil && !req.GRPC.Web,
+	}
+	if t := req.Timeouts; t != nil {
+		if t.Connect > 0 {
+			cfg.connectTimeout = time.Duration(t.Connect)
+		}
+		if t.TLSHandshake > 0 {
+			cfg.tlsHandshakeTimeout = time.Duration(t.TLSHandshake)
+		}
+		if t.ResponseHeader > 0 {
+			cfg.responseHeaderTimeout = time.Duration(t.ResponseHeader)
+		}
+		if t.Total > 0 {
+			cfg.timeout = time.Duration(t.Total)
+		}
+	}
+	if t := req.TLS; t != nil {
+		if t.CACert != "" {
+			cfg.caCert = t.CACert
+		}
+		if t.Cert != "" {
+			cfg.cert = t.Cert
+			cfg.key = t.Key
+		}
+		if t.ServerName != "" {
+			cfg.serverName = t.ServerName
+		}
+		cfg.insecure = cfg.insecure || t.Insecure
+	}
+	if req.Proxy != "" {
+		cfg.proxy = req.Proxy
+	}
+	if req.NoProxy != "" {
+		cfg.noProxy = req.NoProxy
+	}
+	return cfg
+}
+
+// client returns the shared client for req, building it on first use.
+func (r *RequestRunnerImpl) client(req *types.PokeRequest) (*http.Client, error) {
+	cfg := r.clientConfigFor(req)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.clients[cfg]; ok {
+		return c, nil
+	}
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.clients[cfg] = c
+	return c, nil
+}
+
+func newClient(cfg clientConfig) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := newProxyFunc(cfg.proxy, cfg.noProxy)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{
+		Timeout:   cfg.connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   cfg.tlsHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.responseHeaderTimeout,
+		DisableKeepAlives:     cfg.noKeepAlive,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.maxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if cfg.http2Only {
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.timeout,
+	}, nil
+}
+
+// newProxyFunc picks the proxy for each request. An explicit proxy wins over
+// HTTP_PROXY/HTTPS_PROXY/NO_PROXY; noProxy hosts always connect directly.
+func newProxyFunc(proxy, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	if proxy == "" {
+		return func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(req.URL.Hostname(), noProxy) {
+				return nil, nil
+			}
+			return http.ProxyFromEnvironment(req)
+		}, nil
+	}
+
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", proxyURL.Scheme)
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// bypassProxy reports whether host matches the comma-separated noProxy list.
+// Entries may be "*", a domain (matching its subdomains), an IP, or a CIDR.
+func bypassProxy(host, noProxy string) bool {
+	if noProxy == "" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	for entry := range strings.SplitSeq(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case ip != nil && strings.Contains(entry, "/"):
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && cidr.Contains(ip) {
+				return true
+			}
+		default:
+			entry = strings.TrimPrefix(entry, ".")
+			h := strings.ToLower(host)
+			if h == entry || strings.HasSuffix(h, "."+entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// proxyFor returns the redacted proxy URL the client would use for httpReq.
+func proxyFor(client *http.Client, httpReq *http.Request) string {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		return ""
+	}
+	proxyURL, err := transport.Proxy(httpReq)
+	if err != nil || proxyURL == nil {
+		return ""
+	}
+	return proxyURL.Redacted()
+}
+
+// newTLSConfig builds the client TLS settings. A custom CA bundle is added to
+// the system pool; a cert without a key is assumed to hold both in one PEM.
+func newTLSConfig(cfg clientConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.serverName,
+		InsecureSkipVerify: cfg.insecure,
+	}
+
+	if cfg.caCert != "" {
+		pem, err := os.ReadFile(cfg.caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.cert != "" {
+		key := cfg.key
+		if key == "" {
+			key = cfg.cert
+		}
+		pair, err := tls.LoadX509KeyPair(cfg.cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	} else if cfg.key != "" {
+		return nil, fmt.Errorf("a client key was given without a client certificate")
+	}
+
+	return tlsConfig, nil
+}
+
+// describeTLSError turns common handshake failures into an actionable message.
+// Errors unrelated to TLS are returned unchanged.
+func describeTLSError(err error) error {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var record tls.RecordHeaderError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("TLS handshake failed: server certificate is signed by an unknown authority (try --cacert or --insecure): %w", err)
+	case errors.As(err, &hostname):
+		return fmt.Errorf("TLS handshake failed: server certificate does not match the host (try --servername): %w", err)
+	case errors.As(err, &invalid):
+		return fmt.Errorf("TLS handshake failed: server certificate is invalid: %w", err)
+	case strings.Contains(err.Error(), "remote error: tls:"):
+		return fmt.Errorf("TLS handshake failed: server rejected the connection, it may require a client certificate (try --cert/--key): %w", err)
+	case errors.As(err, &record):
+		return fmt.Errorf("TLS handshake failed: server did not respond with TLS, check the scheme and port: %w", err)
+	}
+	return err
+}
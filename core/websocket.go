@@ -0,0 +1,385 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"poke/types"
+	"poke/util"
+)
+
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+
+	wsGUID          = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsMaxMessage    = 32 << 20        // largest message poke will buffer
+	wsScriptTimeout = 5 * time.Second // saved or scripted sessions with no limit set
+	wsCloseWait     = 1 * time.Second // how long to wait for the server's close reply
+)
+
+var errWSClosed = errors.New("websocket closed")
+
+// WebSocket upgrades req's URL to a WebSocket and exchanges messages until
+// the server closes, MaxMessages arrive, the timeout passes or ctx is
+// canceled. Received messages are printed and checked against req.Assert.
+func (r *RequestRunnerImpl) WebSocket(ctx context.Context, req *types.PokeRequest) error {
+	ws := r.webSocketFor(req)
+	if ws.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(ws.Timeout))
+		defer cancel()
+	}
+
+	start := time.Now()
+	conn, rawResp, err := r.dialWebSocket(ctx, req, ws)
+	if err != nil {
+		return err
+	}
+	defer conn.rw.Close()
+	util.Info("Connected to %s (%s)", req.FullURL, util.ColorStatus(rawResp.StatusCode))
+	if proto := rawResp.Header.Get("Sec-WebSocket-Protocol"); proto != "" {
+		util.Info("Subprotocol: %s", proto)
+	}
+
+	received := make(chan types.StreamEvent)
+	readDone := make(chan error, 1)
+	go func() {
+		defer close(received)
+		for {
+			op, data, err := conn.read()
+			if err != nil {
+				readDone <- err
+				return
+			}
+			ev := types.StreamEvent{Event: "text", Data: string(data), Received: time.Now()}
+			if op == wsBinary {
+				ev.Event, ev.Data = "binary", base64.StdEncoding.EncodeToString(data)
+			}
+			select {
+			case received <- ev:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	if len(ws.Messages) > 0 {
+		go func() {
+			for _, raw := range ws.Messages {
+				msg := wsMessage(raw)
+				if err := conn.write(wsText, []byte(msg)); err != nil {
+					return
+				}
+				util.Info("Sent: %s", msg)
+			}
+		}()
+	} else if req.Source == "" {
+		// only an interactive `poke ws <url>` session reads stdin; a saved
+		// session without messages just listens
+		go func() {
+			scanner := bufio.NewScanner(os.Stdin)
+			scanner.Buffer(make([]byte, 64*1024), wsMaxMessage)
+			for scanner.Scan() {
+				if err := conn.write(wsText, scanner.Bytes()); err != nil {
+					return
+				}
+			}
+			// end of input starts the closing handshake; replies the server
+			// already queued still arrive before its close frame
+			conn.close(1000)
+		}()
+	}
+
+	events := []types.StreamEvent{}
+	for done := false; !done; {
+		select {
+		case ev, ok := <-received:
+			if !ok {
+				done = true
+				break
+			}
+			events = append(events, ev)
+			util.PrintEvent(ev)
+			done = ws.MaxMessages > 0 && len(events) >= ws.MaxMessages
+		case <-ctx.Done():
+			done = true
+		}
+	}
+	conn.close(1000)
+	go func() {
+		for range received {
+		}
+	}()
+	select {
+	case err := <-readDone:
+		if err != nil && !errors.Is(err, io.EOF) && ctx.Err() == nil {
+			util.Warn("Connection ended: %v", err)
+		}
+	case <-time.After(wsCloseWait):
+	}
+
+	resp := &types.PokeResponse{
+		StatusCode: rawResp.StatusCode,
+		Status:     rawResp.Status,
+		Headers:    rawResp.Header,
+		Timestamp:  time.Now(),
+		Duration:   time.Since(start),
+		Events:     events,
+	}
+	_ = r.SaveResponse(resp)
+	util.Info("%d messages received in %v", len(events), resp.Duration.Round(time.Millisecond))
+	if req.Assert != nil {
+		if ok, err := r.verify(resp, req.Assert); !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// webSocketFor merges req's websocket block with the CLI flags, which fill
+// in anything the block leaves unset.
+func (r *RequestRunnerImpl) webSocketFor(req *types.PokeRequest) types.WebSocketOptions {
+	var ws types.WebSocketOptions
+	if req.WebSocket != nil {
+		ws = *req.WebSocket
+	}
+	if ws.MaxMessages == 0 {
+		ws.MaxMessages = r.Opts.MaxEvents
+	}
+	if ws.Timeout == 0 {
+		ws.Timeout = types.Duration(r.Opts.WSTimeout)
+	}
+	if len(ws.Subprotocols) == 0 && r.Opts.Subprotocol != "" {
+		ws.Subprotocols = strings.Split(r.Opts.Subprotocol, ",")
+	}
+	if (len(ws.Messages) > 0 || req.Source != "") && ws.MaxMessages == 0 && ws.Timeout == 0 {
+		ws.Timeout = types.Duration(wsScriptTimeout)
+	}
+	return ws
+}
+
+// wsMessage turns a scripted message into frame text: JSON strings are sent
+// as their value, anything else as compact JSON.
+func wsMessage(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return string(raw)
+	}
+	return compact.String()
+}
+
+// dialWebSocket performs the upgrade through the runner's client, so the
+// request's headers, auth, signing, TLS and proxy settings all apply.
+func (r *RequestRunnerImpl) dialWebSocket(ctx context.Context, req *types.PokeRequest, ws types.WebSocketOptions) (*wsConn, *http.Response, error) {
+	u, err := url.Parse(req.FullURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse URL: %w", err)
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, nil, fmt.Errorf("unsupported websocket scheme %q (use ws or wss)", u.Scheme)
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	upgrade := *req
+	upgrade.FullURL = u.String()
+	upgrade.Method = http.MethodGet
+	upgrade.Body, upgrade.BodyFile, upgrade.BodyStdin = "", "", false
+	upgrade.Form, upgrade.Multipart, upgrade.SSE = nil, nil, nil
+	upgrade.Headers = maps.Clone(req.Headers)
+	if upgrade.Headers == nil {
+		upgrade.Headers = map[string][]string{}
+	}
+	upgrade.Headers["Connection"] = []string{"Upgrade"}
+	upgrade.Headers["Upgrade"] = []string{"websocket"}
+	upgrade.Headers["Sec-WebSocket-Version"] = []string{"13"}
+	upgrade.Headers["Sec-WebSocket-Key"] = []string{key}
+	if len(ws.Subprotocols) > 0 {
+		upgrade.Headers["Sec-WebSocket-Protocol"] = []string{strings.Join(ws.Subprotocols, ", ")}
+	}
+
+	client, err := r.client(&upgrade)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the total timeout would cut the connection mid-session and hide the
+	// upgraded body, so the session timeout takes its place
+	noTimeout := *client
+	noTimeout.Timeout = 0
+	rawResp, _, _, err := r.do(ctx, &noTimeout, &upgrade)
+	if err != nil {
+		return nil, nil, describeTLSError(err)
+	}
+	if rawResp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(rawResp.Body, 512))
+		rawResp.Body.Close()
+		return nil, nil, fmt.Errorf("websocket upgrade failed: %s %s", rawResp.Status, strings.TrimSpace(string(body)))
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	if rawResp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		rawResp.Body.Close()
+		return nil, nil, fmt.Errorf("websocket upgrade failed: invalid Sec-WebSocket-Accept")
+	}
+	rw, ok := rawResp.Body.(io.ReadWriteCloser)
+	if !ok {
+		rawResp.Body.Close()
+		return nil, nil, fmt.Errorf("websocket upgrade failed: connection is not writable")
+	}
+	return &wsConn{rw: rw, br: bufio.NewReader(rw)}, rawResp, nil
+}
+
+// wsConn frames messages over an upgraded connection (RFC 6455, client side).
+type wsConn struct {
+	rw io.ReadWriteCloser
+	br *bufio.Reader
+
+	mu     sync.Mutex // serialises writes
+	closed bool       // close frame sent
+}
+
+// write sends one masked frame. Nothing may be sent after a close frame.
+func (c *wsConn) write(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errWSClosed
+	}
+	if op == wsClose {
+		c.closed = true
+	}
+
+	header := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 0x80|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	var mask [4]byte
+	rand.Read(mask[:])
+	header = append(header, mask[:]...)
+
+	frame := append(header, payload...)
+	masked := frame[len(header):]
+	for i := range masked {
+		masked[i] ^= mask[i%4]
+	}
+	_, err := c.rw.Write(frame)
+	return err
+}
+
+// close starts the closing handshake with the given status code.
+func (c *wsConn) close(code uint16) {
+	c.write(wsClose, binary.BigEndian.AppendUint16(nil, code))
+}
+
+// read returns the next complete data message, answering pings and
+// reassembling fragments. A close frame from the server ends with io.EOF.
+func (c *wsConn) read() (byte, []byte, error) {
+	var msgOp byte
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsPing:
+			c.write(wsPong, payload)
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			c.write(wsClose, payload[:min(len(payload), 2)])
+			return 0, nil, io.EOF
+		case wsContinuation:
+			msg = append(msg, payload...)
+		default:
+			msgOp, msg = op, payload
+		}
+		if len(msg) > wsMaxMessage {
+			return 0, nil, fmt.Errorf("message larger than %s", util.FormatBytes(wsMaxMessage))
+		}
+		if fin {
+			return msgOp, msg, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(c.br, h[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op, masked := h[0]&0x80 != 0, h[0]&0x0F, h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxMessage {
+		return false, 0, nil, fmt.Errorf("frame larger than %s", util.FormatBytes(wsMaxMessage))
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
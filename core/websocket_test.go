@@ -0,0 +1,125 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeWSConn feeds server frames to a wsConn and records the frames it writes.
+type fakeWSConn struct {
+	in  *bytes.Reader
+	out bytes.Buffer
+}
+
+func (f *fakeWSConn) Read(p []byte) (int, error)  { return f.in.Read(p) }
+func (f *fakeWSConn) Write(p []byte) (int, error) { return f.out.Write(p) }
+func (f *fakeWSConn) Close() error                { return nil }
+
+func newFakeWSConn(server []byte) (*wsConn, *fakeWSConn) {
+	f := &fakeWSConn{in: bytes.NewReader(server)}
+	return &wsConn{rw: f, br: bufio.NewReader(f)}, f
+}
+
+// The frames are the examples in RFC 6455 section 5.7.
+func TestWSConnRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  []byte
+		wantOp  byte
+		want    string
+		wantErr error
+	}{
+		{"unmasked text", []byte{0x81, 0x05, 'H', 'e', 'l', 'l', 'o'}, wsText, "Hello", nil},
+		{"masked text", []byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58}, wsText, "Hello", nil},
+		{"fragmented", []byte{0x01, 0x03, 'H', 'e', 'l', 0x80, 0x02, 'l', 'o'}, wsText, "Hello", nil},
+		{"ping before message", []byte{0x89, 0x05, 'H', 'e', 'l', 'l', 'o', 0x81, 0x01, '!'}, wsText, "!", nil},
+		{"16-bit length", append([]byte{0x82, 0x7E, 0x01, 0x00}, bytes.Repeat([]byte{'a'}, 256)...), wsBinary, strings.Repeat("a", 256), nil},
+		{"64-bit length", append([]byte{0x82, 0x7F, 0, 0, 0, 0, 0, 1, 0, 0}, bytes.Repeat([]byte{'b'}, 65536)...), wsBinary, strings.Repeat("b", 65536), nil},
+		{"close", []byte{0x88, 0x02, 0x03, 0xE8}, 0, "", io.EOF},
+		{"truncated", []byte{0x81, 0x05, 'H', 'e'}, 0, "", io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newFakeWSConn(tt.frames)
+			op, msg, err := c.read()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if op != tt.wantOp || string(msg) != tt.want {
+				t.Errorf("got op %d %q, want op %d %q", op, truncate(msg), tt.wantOp, truncate([]byte(tt.want)))
+			}
+		})
+	}
+}
+
+func TestWSConnWriteMasksFrames(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		headerSize int // bytes before the mask
+	}{
+		{"7-bit length", 5, 2},
+		{"16-bit length", 126, 4},
+		{"64-bit length", 70000, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, f := newFakeWSConn(nil)
+			payload := bytes.Repeat([]byte{'x'}, tt.size)
+			if err := c.write(wsText, payload); err != nil {
+				t.Fatal(err)
+			}
+			frame := f.out.Bytes()
+			if frame[0] != 0x80|wsText {
+				t.Errorf("first byte = %#x, want FIN and text", frame[0])
+			}
+			if frame[1]&0x80 == 0 {
+				t.Fatal("client frame is not masked")
+			}
+			if len(frame) != tt.headerSize+4+tt.size {
+				t.Fatalf("frame is %d bytes, want %d", len(frame), tt.headerSize+4+tt.size)
+			}
+			if bytes.Equal(frame[tt.headerSize+4:], payload) {
+				t.Error("payload was sent unmasked")
+			}
+
+			// the frame decodes back to the payload
+			back, _ := newFakeWSConn(frame)
+			fin, op, got, err := back.readFrame()
+			if err != nil || !fin || op != wsText || !bytes.Equal(got, payload) {
+				t.Errorf("decoded fin=%v op=%d len=%d err=%v", fin, op, len(got), err)
+			}
+		})
+	}
+}
+
+func TestWSConnAnswersPingAndClose(t *testing.T) {
+	c, f := newFakeWSConn([]byte{0x89, 0x02, 'h', 'i', 0x88, 0x02, 0x03, 0xE8})
+	if _, _, err := c.read(); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF after close", err)
+	}
+	replies, _ := newFakeWSConn(f.out.Bytes())
+	for _, want := range []struct {
+		op      byte
+		payload string
+	}{{wsPong, "hi"}, {wsClose, "\x03\xe8"}} {
+		_, op, payload, err := replies.readFrame()
+		if err != nil || op != want.op || string(payload) != want.payload {
+			t.Errorf("reply op=%d payload=%q err=%v, want op=%d payload=%q", op, payload, err, want.op, want.payload)
+		}
+	}
+	if err := c.write(wsText, []byte("late")); err != errWSClosed {
+		t.Errorf("write after close = %v, want errWSClosed", err)
+	}
+}
+
+func truncate(b []byte) string {
+	if len(b) > 16 {
+		return string(b[:16]) + "..."
+	}
+	return string(b)
+}
@@ -3,8 +3,12 @@ module poke
 go 1.24.1
 
 require (
+	github.com/bufbuild/protocompile v0.14.1
 	github.com/fatih/color v1.18.0
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.3
 	golang.org/x/term v0.31.0
+	golang.org/x/text v0.17.0
+	google.golang.org/protobuf v1.36.6
 )
 
 require (
@@ -21,6 +25,7 @@ require (
 	github.com/shopspring/decimal v1.4.0 // indirect
 	github.com/spf13/cast v1.7.0 // indirect
 	golang.org/x/crypto v0.26.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
 )
 
 require (
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
 	"time"
 )
@@ -22,6 +24,63 @@ type CLIOptions struct {
 	Editor       bool
 	SavePath     string
 	Help         bool
+	BenchOut     string
+	Rate         float64
+	Duration     time.Duration
+	RampUp       time.Duration
+
+	Timeout               time.Duration
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	NoKeepAlive           bool
+
+	CACert     string
+	Cert       string
+	Key        string
+	Insecure   bool
+	ServerName string
+
+	Proxy   string
+	NoProxy string
+
+	User      string
+	Bearer    string
+	Digest    bool
+	Netrc     bool
+	NetrcFile string
+
+	Sign          string
+	AWSRegion     string
+	AWSService    string
+	HMACSecret    string
+	HMACKeyID     string
+	HMACAlgorithm string
+	HMACHeader    string
+	HMACLayout    string
+
+	FormFields []string
+	Form       bool
+
+	Output   string
+	MaxBody  int64
+	Continue bool
+
+	SSE          bool
+	MaxEvents    int
+	SSETimeout   time.Duration
+	SSEReconnect int
+
+	WSTimeout   time.Duration
+	Subprotocol string
+
+	GraphQL   string
+	Variables string
+	Operation string
+
+	Schema string
+
+	UpdateSnapshots bool
 }
 
 type PokeResponse struct {
@@ -33,6 +92,51 @@ type PokeResponse struct {
 	Raw         *http.Response      `json:"-"` // not serializable
 	Timestamp   time.Time           `json:"timestamp"`
 	Duration    time.Duration       `json:"duration"`
+	Proxy       string              `json:"proxy,omitempty"`
+	Timings     *Timings            `json:"timings,omitempty"`
+	Bytes       int64               `json:"bytes"`                // body bytes received, kept or not
+	Truncated   bool                `json:"truncated,omitempty"`  // Body was cut short by --max-body
+	Output      string              `json:"output,omitempty"`     // file the body was written to
+	Resumed     int64               `json:"resumed_at,omitempty"` // offset a --continue download picked up from
+	Events      []StreamEvent       `json:"events,omitempty"`     // messages received on a stream
+	Trailers    map[string][]string `json:"trailers,omitempty"`
+	GRPC        *GRPCStatus         `json:"grpc,omitempty"`
+	GraphQL     []GraphQLError      `json:"graphql_errors,omitempty"`
+}
+
+// GraphQLError is one entry of a GraphQL response's errors array.
+type GraphQLError struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// GRPCStatus is the status a gRPC call ended with.
+type GRPCStatus struct {
+	Code    int    `json:"code"`
+	Name    string `json:"name"` // e.g. OK, NOT_FOUND
+	Message string `json:"message,omitempty"`
+}
+
+// StreamEvent is one message received on a stream. For Server-Sent Events
+// ID, Event and Retry come from the matching fields; Event defaults to "message".
+type StreamEvent struct {
+	ID       string    `json:"id,omitempty"`
+	Event    string    `json:"event,omitempty"`
+	Data     string    `json:"data"`
+	Retry    int       `json:"retry,omitempty"` // reconnection delay in milliseconds
+	Received time.Time `json:"received"`
+}
+
+// Timings breaks a response down by phase. Phases that did not happen, such as
+// DNS and connect on a reused connection, are zero.
+type Timings struct {
+	DNS      time.Duration `json:"dns"`
+	Connect  time.Duration `json:"connect"`
+	TLS      time.Duration `json:"tls"`
+	TTFB     time.Duration `json:"ttfb"` // request written to first response byte
+	Transfer time.Duration `json:"transfer"`
+	Total    time.Duration `json:"total"`
 }
 
 type PokeRequest struct {
@@ -46,18 +150,196 @@ type PokeRequest struct {
 	QueryParams map[string][]string `json:"query_params"`
 	Body        string              `json:"body"`
 	BodyFile    string              `json:"body_file"`
+	BodyStdin   bool                `json:"-"`
 	Meta        *Meta               `json:"meta"`
 	Retries     int                 `json:"retries"`
 	Backoff     int                 `josn:"backoff"`
 	Repeat      int                 `json:"repeat"`
 	Workers     int                 `json:"workers"`
+	Rate        float64             `json:"rate,omitempty"`     // requests per second, open model
+	Duration    Duration            `json:"duration,omitempty"` // run for this long instead of Repeat times
+	RampUp      Duration            `json:"ramp_up,omitempty"`
 	Assert      *Assertions         `json:"assert"`
+	Timeouts    *Timeouts           `json:"timeouts,omitempty"`
+	NoKeepAlive bool                `json:"no_keepalive,omitempty"`
+	TLS         *TLSOptions         `json:"tls,omitempty"`
+	Proxy       string              `json:"proxy,omitempty"`
+	NoProxy     string              `json:"no_proxy,omitempty"`
+	Auth        *Auth               `json:"auth,omitempty"`
+	Sign        *Signing            `json:"sign,omitempty"`
+	Form        map[string][]string `json:"form,omitempty"`      // sent as application/x-www-form-urlencoded
+	Multipart   []FormPart          `json:"multipart,omitempty"` // sent as multipart/form-data
+	Boundary    string              `json:"-"`
+	SSE         *SSEOptions         `json:"sse,omitempty"`
+	WebSocket   *WebSocketOptions   `json:"websocket,omitempty"`
+	GRPC        *GRPCOptions        `json:"grpc,omitempty"`
+	GraphQL     *GraphQL            `json:"graphql,omitempty"`
+	Snapshot    *SnapshotOptions    `json:"snapshot,omitempty"`
+	Source      string              `json:"-"` // file the request was loaded from
+	AuthToken   string              `json:"-"` // OAuth2 token rendered into {{ auth.token }} at load
+}
+
+// SnapshotOptions tune the comparison against a request's .snap.json file.
+type SnapshotOptions struct {
+	Ignore []string `json:"ignore,omitempty"` // JSON paths into the body, e.g. $.created_at
+}
+
+// GraphQL is sent as the standard {"query", "variables", "operationName"}
+// JSON body. Query holds the document or a path to a .graphql/.gql file.
+type GraphQL struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+// GRPCOptions turns the request into a gRPC call of Service/Method, with the
+// schema taken from .proto files or a compiled descriptor set.
+type GRPCOptions struct {
+	Service       string          `json:"service"` // fully qualified, e.g. helloworld.Greeter
+	Method        string          `json:"method"`
+	Message       json.RawMessage `json:"message,omitempty"` // request message as protobuf JSON
+	Protos        []string        `json:"protos,omitempty"`
+	ImportPaths   []string        `json:"import_paths,omitempty"`   // defaults to each proto's directory
+	DescriptorSet string          `json:"descriptor_set,omitempty"` // from protoc --descriptor_set_out or buf build
+	Web           bool            `json:"web,omitempty"`            // gRPC-Web over HTTP/1.1 instead of gRPC over HTTP/2
+}
+
+// WebSocketOptions drives a WebSocket session. With Messages set the session
+// is scripted; a saved session without them only listens, and an interactive
+// `poke ws <url>` sends lines read from stdin.
+type WebSocketOptions struct {
+	Messages     []json.RawMessage `json:"messages,omitempty"`     // sent in order: strings as-is, anything else as JSON text
+	MaxMessages  int               `json:"max_messages,omitempty"` // stop after receiving this many
+	Timeout      Duration          `json:"timeout,omitempty"`      // end the session after this long
+	Subprotocols []string          `json:"subprotocols,omitempty"`
+}
+
+// SSEOptions reads the response as a Server-Sent Events stream. Responses
+// with a text/event-stream content type are streamed even without it.
+type SSEOptions struct {
+	MaxEvents   int      `json:"max_events,omitempty"`    // stop after this many events
+	Timeout     Duration `json:"timeout,omitempty"`       // stop listening after this long
+	Reconnect   int      `json:"reconnect,omitempty"`     // reconnects allowed when the server ends the stream
+	LastEventID string   `json:"last_event_id,omitempty"` // resume from this event on the first connection
+}
+
+// FormPart is one multipart/form-data field. When File is set the file is
+// streamed from disk; otherwise Value is sent.
+type FormPart struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	File        string `json:"file,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Signing signs the final request right before it is sent. Type is sigv4
+// (credentials from AWS_* env vars) or hmac.
+type Signing struct {
+	Type    string `json:"type"`
+	Region  string `json:"region,omitempty"`
+	Service string `json:"service,omitempty"`
+
+	Secret          string `json:"secret,omitempty"`
+	KeyID           string `json:"key_id,omitempty"`
+	Algorithm       string `json:"algorithm,omitempty"`        // sha256 (default), sha512 or sha1
+	Layout          string `json:"layout,omitempty"`           // canonical string, e.g. "{method}\n{path}\n{body_sha256}"
+	Header          string `json:"header,omitempty"`           // defaults to X-Signature
+	Format          string `json:"format,omitempty"`           // header value, e.g. "HMAC {key_id}:{signature}"
+	Encoding        string `json:"encoding,omitempty"`         // hex (default) or base64
+	TimestampHeader string `json:"timestamp_header,omitempty"` // header that carries {timestamp}
+}
+
+// Auth describes how a request authenticates. Type is basic, bearer, digest,
+// netrc or oauth2. Values can be templated, e.g. "password": "{{ env.API_PASS }}",
+// so secrets stay out of the saved headers.
+type Auth struct {
+	Type      string  `json:"type"`
+	Username  string  `json:"username,omitempty"`
+	Password  string  `json:"password,omitempty"`
+	Token     string  `json:"token,omitempty"`
+	NetrcFile string  `json:"netrc_file,omitempty"`
+	OAuth2    *OAuth2 `json:"oauth2,omitempty"`
+}
+
+// OAuth2 configures fetching an access token before the request is sent.
+// GrantType is client_credentials (default) or refresh_token.
+type OAuth2 struct {
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	GrantType    string   `json:"grant_type,omitempty"`
+	RefreshToken string   `json:"refresh_token,omitempty"`
+	AuthStyle    string   `json:"auth_style,omitempty"` // "basic" (default) or "params"
+}
+
+// Timeouts overrides the CLI transport timeouts for a single request.
+// Zero values fall back to the CLI options.
+type Timeouts struct {
+	Connect        Duration `json:"connect,omitempty"`
+	TLSHandshake   Duration `json:"tls_handshake,omitempty"`
+	ResponseHeader Duration `json:"response_header,omitempty"`
+	Total          Duration `json:"total,omitempty"`
+}
+
+// TLSOptions configures certificate verification and client certificates.
+// Empty fields fall back to the CLI options.
+type TLSOptions struct {
+	CACert     string `json:"ca_cert,omitempty"`
+	Cert       string `json:"cert,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Insecure   bool   `json:"insecure,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
 }
 
 type Assertions struct {
 	Status       int                 `json:"status"`
 	BodyContains string              `json:"body_contains"`
 	Headers      map[string][]string `json:"headers"`
+	// comma-separated codes, classes and ranges, e.g. "2xx", "200-299,304"
+	StatusRange     string              `json:"status_range,omitempty"`
+	MaxDuration     Duration            `json:"max_duration,omitempty"`
+	MaxBodyBytes    int64               `json:"max_body_bytes,omitempty"`
+	MinBodyBytes    int64               `json:"min_body_bytes,omitempty"`
+	BodyNotContains string              `json:"body_not_contains,omitempty"`
+	BodyMatches     string              `json:"body_matches,omitempty"`   // regular expression
+	HeaderMatches   map[string]string   `json:"header_matches,omitempty"` // header name to regular expression
+	HeaderAbsent    []string            `json:"header_absent,omitempty"`
+	Events          *StreamAssertions   `json:"events,omitempty"`
+	GRPCStatus      string              `json:"grpc_status,omitempty"` // code name or number; gRPC calls must be OK otherwise
+	Trailers        map[string][]string `json:"trailers,omitempty"`
+	// GraphQL responses fail on a non-empty errors array unless this is set
+	AllowGraphQLErrors bool            `json:"allow_graphql_errors,omitempty"`
+	JSON               []JSONAssertion `json:"json,omitempty"`
+	// JSON Schema (draft 2020-12) for the body: a file path string or an inline schema
+	Schema json.RawMessage `json:"schema,omitempty"`
+	// template expressions without braces that must be true, e.g. "eq .status 200"
+	Expr []string `json:"expr,omitempty"`
+}
+
+// JSONAssertion checks the value at a JSONPath in the response body, such as
+// $.data.items[0].id. Paths with a wildcard select the list of all matches.
+type JSONAssertion struct {
+	Path  string `json:"path"`
+	Op    string `json:"op,omitempty"` // eq (default), ne, gt, lt, exists, matches or len
+	Value any    `json:"value,omitempty"`
+}
+
+// UnmarshalJSON keeps a numeric Value as json.Number, so large integers are
+// compared exactly.
+func (a *JSONAssertion) UnmarshalJSON(data []byte) error {
+	type plain JSONAssertion
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode((*plain)(a))
+}
+
+// StreamAssertions check the messages received on a stream.
+type StreamAssertions struct {
+	MinCount int      `json:"min_count,omitempty"` // at least this many messages
+	Types    []string `json:"types,omitempty"`     // each type was received at least once
+	Contains []string `json:"contains,omitempty"`  // each string appears in some message's data
 }
 
 type Meta struct {
@@ -71,4 +353,56 @@ type BenchmarkResult struct {
 	Successes int
 	Failures  int
 	Durations []time.Duration
+	Timings   []Timings
+
+	StatusCodes map[int]int    // responses received, by status code
+	Errors      map[string]int // failures, by error class
+	Samples     []BenchmarkSample
+}
+
+// BenchmarkSample is a single request from a benchmark run. Durations are in
+// nanoseconds when exported.
+type BenchmarkSample struct {
+	Num     int           `json:"num"`
+	Offset  time.Duration `json:"offset"`
+	Latency time.Duration `json:"latency"`
+	Status  int           `json:"status"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// BenchmarkReport is the machine-readable result written by --bench-out and
+// read back by `poke bench compare`.
+type BenchmarkReport struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	CreatedAt time.Time         `json:"created_at"`
+	Summary   BenchmarkSummary  `json:"summary"`
+	Samples   []BenchmarkSample `json:"samples"`
+}
+
+type BenchmarkSummary struct {
+	Total       int            `json:"total"`
+	Successes   int            `json:"successes"`
+	Failures    int            `json:"failures"`
+	TotalTime   time.Duration  `json:"total_time"`
+	Throughput  float64        `json:"throughput"`
+	Workers     int            `json:"workers"`
+	Rate        float64        `json:"rate,omitempty"`
+	Latency     LatencyStats   `json:"latency"`
+	StatusCodes map[int]int    `json:"status_codes"`
+	Errors      map[string]int `json:"errors"`
+}
+
+// LatencyStats summarises a set of request latencies.
+type LatencyStats struct {
+	Count  int           `json:"count"`
+	Min    time.Duration `json:"min"`
+	Max    time.Duration `json:"max"`
+	Mean   time.Duration `json:"mean"`
+	StdDev time.Duration `json:"stddev"`
+	P50    time.Duration `json:"p50"`
+	P90    time.Duration `json:"p90"`
+	P95    time.Duration `json:"p95"`
+	P99    time.Duration `json:"p99"`
+	P999   time.Duration `json:"p99_9"`
 }
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"poke/types"
+)
+
+// assertStatusRange checks code against a comma-separated list of codes
+// ("404"), classes ("2xx") and inclusive ranges ("200-299").
+func assertStatusRange(code int, spec string) error {
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		lo, hi, err := statusBounds(part)
+		if err != nil {
+			return err
+		}
+		if code >= lo && code <= hi {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected status in %s, got %d", spec, code)
+}
+
+func statusBounds(part string) (int, int, error) {
+	if len(part) == 3 && strings.HasSuffix(part, "xx") && part[0] >= '1' && part[0] <= '5' {
+		lo := int(part[0]-'0') * 100
+		return lo, lo + 99, nil
+	}
+	if from, to, ok := strings.Cut(part, "-"); ok {
+		lo, err1 := strconv.Atoi(strings.TrimSpace(from))
+		hi, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 == nil && err2 == nil && lo <= hi {
+			return lo, hi, nil
+		}
+	} else if n, err := strconv.Atoi(part); err == nil {
+		return n, n, nil
+	}
+	return 0, 0, fmt.Errorf("invalid status_range %q (use e.g. 2xx, 200-299 or 404)", part)
+}
+
+func assertBodySize(resp *types.PokeResponse, assertions *types.Assertions) error {
+	size := max(resp.Bytes, int64(len(resp.Body)))
+	if assertions.MaxBodyBytes > 0 && size > assertions.MaxBodyBytes {
+		return fmt.Errorf("expected a body of at most %s, got %s", FormatBytes(assertions.MaxBodyBytes), FormatBytes(size))
+	}
+	if assertions.MinBodyBytes > 0 && size < assertions.MinBodyBytes {
+		return fmt.Errorf("expected a body of at least %s, got %s", FormatBytes(assertions.MinBodyBytes), FormatBytes(size))
+	}
+	return nil
+}
+
+func assertMatches(what, value, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid %s pattern %q: %w", what, pattern, err)
+	}
+	if !re.MatchString(value) {
+		return fmt.Errorf("expected %s to match %q, got %q", what, pattern, value)
+	}
+	return nil
+}
+
+// assertHeaderMatches passes a header when any of its values matches.
+func assertHeaderMatches(headers map[string][]string, patterns map[string]string) error {
+	var errs []error
+	for _, k := range slices.Sorted(maps.Keys(patterns)) {
+		vals := http.Header(headers).Values(k)
+		if len(vals) == 0 {
+			errs = append(errs, fmt.Errorf("expected header %q to match %q, but it is missing", k, patterns[k]))
+			continue
+		}
+		var err error
+		for _, v := range vals {
+			if err = assertMatches("header "+strconv.Quote(k), v, patterns[k]); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"poke/types"
+)
+
+const (
+	boxWidth       = 40 // columns between the box borders
+	histogramRows  = 10
+	histogramWidth = 20
+)
+
+func PrintBenchmarkResults(res types.BenchmarkResult, totalTime float64, req *types.PokeRequest) {
+	fmt.Println("╭──────────── Poke Benchmark ────────────╮")
+	boxRow("Requests", fmt.Sprintf("%d", res.Total))
+	boxRow("Success", ColorString(fmt.Sprintf("%d", res.Successes), "green"))
+	boxRow("Failures", ColorString(fmt.Sprintf("%d", res.Failures), "red"))
+	boxRow("Total time", fmt.Sprintf("%.2fs", totalTime))
+
+	stats := ComputeLatencyStats(res.Durations)
+	if stats.Count == 0 {
+		boxRow("Avg duration", "N/A")
+		boxRow("Min", "N/A")
+		boxRow("Max", "N/A")
+	} else {
+		boxRow("Avg duration", stats.Mean.Round(time.Microsecond).String())
+		boxRow("Std dev", stats.StdDev.Round(time.Microsecond).String())
+		boxRow("Min", ColorString(stats.Min.String(), "blue"))
+		boxRow("Max", ColorString(stats.Max.String(), "yellow"))
+
+		boxSection("Percentiles")
+		boxRow("p50", stats.P50.String())
+		boxRow("p90", stats.P90.String())
+		boxRow("p95", stats.P95.String())
+		boxRow("p99", stats.P99.String())
+		boxRow("p99.9", stats.P999.String())
+
+		boxSection("Histogram")
+		buckets := Histogram(res.Durations, histogramRows)
+		most := 0
+		for _, b := range buckets {
+			most = max(most, b.Count)
+		}
+		for _, b := range buckets {
+			n := b.Count * histogramWidth / most
+			if b.Count > 0 && n == 0 {
+				n = 1
+			}
+			bar := ColorString(strings.Repeat("█", n), "cyan") + strings.Repeat(" ", histogramWidth-n)
+			boxLine(fmt.Sprintf("≤%-9s %s %6d", shortDuration(b.Upper), bar, b.Count))
+		}
+	}
+
+	if len(res.Timings) > 0 {
+		var totals types.Timings
+		for _, t := range res.Timings {
+			totals.DNS += t.DNS
+			totals.Connect += t.Connect
+			totals.TLS += t.TLS
+			totals.TTFB += t.TTFB
+			totals.Transfer += t.Transfer
+		}
+		n := time.Duration(len(res.Timings))
+		boxSection("Phases (avg)")
+		for _, p := range timingPhases(totals) {
+			boxRow(p.name, (p.d / n).Round(time.Microsecond).String())
+		}
+	}
+
+	if len(res.StatusCodes) > 0 {
+		boxSection("Status codes")
+		for _, code := range slices.Sorted(maps.Keys(res.StatusCodes)) {
+			boxRow(ColorStatus(code), fmt.Sprintf("%d", res.StatusCodes[code]))
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		boxSection("Errors")
+		for _, class := range slices.Sorted(maps.Keys(res.Errors)) {
+			boxRow(class, ColorString(fmt.Sprintf("%d", res.Errors[class]), "red"))
+		}
+	}
+
+	boxSection("")
+	throughput := float64(res.Total) / totalTime
+	boxRow("Throughput", fmt.Sprintf("%.2f", throughput))
+	boxRow("Workers", fmt.Sprintf("%d", req.Workers))
+	if req.Rate > 0 {
+		boxRow("Target rate", fmt.Sprintf("%.2f/s", req.Rate))
+	}
+	fmt.Println("╰────────────────────────────────────────╯")
+}
+
+// boxRow prints a "label  value" line inside the benchmark box.
+func boxRow(label, value string) {
+	boxLine(padVisible(label, 14) + " " + value)
+}
+
+// boxLine prints s inside the box borders, padded to the box width.
+func boxLine(s string) {
+	fmt.Printf("│ %s │\n", padVisible(s, boxWidth-2))
+}
+
+// boxSection prints a divider with an optional centered title.
+func boxSection(title string) {
+	if title != "" {
+		title = " " + title + " "
+	}
+	n := boxWidth - utf8.RuneCountInString(title)
+	fmt.Printf("├%s%s%s┤\n", strings.Repeat("─", n/2), title, strings.Repeat("─", n-n/2))
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// padVisible right-pads s to width, ignoring color escape sequences.
+func padVisible(s string, width int) string {
+	visible := utf8.RuneCountInString(ansiPattern.ReplaceAllString(s, ""))
+	if visible >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-visible)
+}
+
+// shortDuration formats d with three significant digits for narrow columns.
+func shortDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.3gs", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.3gms", float64(d)/float64(time.Millisecond))
+	default:
+		return fmt.Sprintf("%.3gµs", float64(d)/float64(time.Microsecond))
+	}
+}
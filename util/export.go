@@ -0,0 +1,252 @@
+package util
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"poke/types"
+)
+
+// NewBenchmarkReport builds the exportable form of a benchmark run.
+func NewBenchmarkReport(res types.BenchmarkResult, totalTime time.Duration, req *types.PokeRequest) types.BenchmarkReport {
+	throughput := 0.0
+	if totalTime > 0 {
+		throughput = float64(res.Total) / totalTime.Seconds()
+	}
+	return types.BenchmarkReport{
+		Method:    req.Method,
+		URL:       req.FullURL,
+		CreatedAt: time.Now(),
+		Summary: types.BenchmarkSummary{
+			Total:       res.Total,
+			Successes:   res.Successes,
+			Failures:    res.Failures,
+			TotalTime:   totalTime,
+			Throughput:  throughput,
+			Workers:     req.Workers,
+			Rate:        req.Rate,
+			Latency:     ComputeLatencyStats(res.Durations),
+			StatusCodes: res.StatusCodes,
+			Errors:      res.Errors,
+		},
+		Samples: res.Samples,
+	}
+}
+
+// WriteBenchmarkReport writes report as JSON, or as CSV when path ends in
+// .csv. The CSV holds one row per sample, preceded by "#" summary lines.
+func WriteBenchmarkReport(report types.BenchmarkReport, path string) error {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return writeBenchmarkCSV(report, path)
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0644)
+}
+
+func writeBenchmarkCSV(report types.BenchmarkReport, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := report.Summary
+	summary := [][2]string{
+		{"method", report.Method},
+		{"url", report.URL},
+		{"total", strconv.Itoa(s.Total)},
+		{"successes", strconv.Itoa(s.Successes)},
+		{"failures", strconv.Itoa(s.Failures)},
+		{"total_time_ms", msString(s.TotalTime)},
+		{"throughput", strconv.FormatFloat(s.Throughput, 'f', 2, 64)},
+		{"mean_ms", msString(s.Latency.Mean)},
+		{"p50_ms", msString(s.Latency.P50)},
+		{"p90_ms", msString(s.Latency.P90)},
+		{"p95_ms", msString(s.Latency.P95)},
+		{"p99_ms", msString(s.Latency.P99)},
+		{"p99_9_ms", msString(s.Latency.P999)},
+	}
+	for _, kv := range summary {
+		fmt.Fprintf(f, "# %s,%s\n", kv[0], kv[1])
+	}
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"num", "offset_ms", "latency_ms", "status", "error"})
+	for _, sample := range report.Samples {
+		w.Write([]string{
+			strconv.Itoa(sample.Num),
+			msString(sample.Offset),
+			msString(sample.Latency),
+			strconv.Itoa(sample.Status),
+			sample.Error,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func msString(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// ReadBenchmarkReport loads a JSON report written by --bench-out.
+func ReadBenchmarkReport(path string) (types.BenchmarkReport, error) {
+	var report types.BenchmarkReport
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("%s is not a JSON benchmark report: %w", path, err)
+	}
+	return report, nil
+}
+
+// MetricDelta compares one summary metric between two benchmark runs.
+type MetricDelta struct {
+	Name     string
+	Old      float64
+	New      float64
+	Change   float64 // percent change, or percentage points for error_rate
+	Worse    bool    // the change is in the regressing direction
+	Exceeded bool    // the change crossed its threshold
+}
+
+type compareMetric struct {
+	name   string
+	higher bool // higher values are better
+	value  func(types.BenchmarkSummary) float64
+}
+
+var compareMetrics = []compareMetric{
+	{"mean", false, func(s types.BenchmarkSummary) float64 { return msFloat(s.Latency.Mean) }},
+	{"p50", false, func(s types.BenchmarkSummary) float64 { return msFloat(s.Latency.P50) }},
+	{"p90", false, func(s types.BenchmarkSummary) float64 { return msFloat(s.Latency.P90) }},
+	{"p95", false, func(s types.BenchmarkSummary) float64 { return msFloat(s.Latency.P95) }},
+	{"p99", false, func(s types.BenchmarkSummary) float64 { return msFloat(s.Latency.P99) }},
+	{"p99.9", false, func(s types.BenchmarkSummary) float64 { return msFloat(s.Latency.P999) }},
+	{"max", false, func(s types.BenchmarkSummary) float64 { return msFloat(s.Latency.Max) }},
+	{"throughput", true, func(s types.BenchmarkSummary) float64 { return s.Throughput }},
+	{"error_rate", false, func(s types.BenchmarkSummary) float64 {
+		if s.Total == 0 {
+			return 0
+		}
+		return float64(s.Failures) / float64(s.Total) * 100
+	}},
+}
+
+func msFloat(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ParseThresholds parses a list such as "p95=10%,throughput=5%" into the
+// allowed regression per metric.
+func ParseThresholds(s string) (map[string]float64, error) {
+	thresholds := map[string]float64{}
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid threshold %q (expected metric=percent)", part)
+		}
+		name = strings.TrimSpace(name)
+		if !slices.ContainsFunc(compareMetrics, func(m compareMetric) bool { return m.name == name }) {
+			return nil, fmt.Errorf("unknown metric %q", name)
+		}
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(val), "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold %q: %w", part, err)
+		}
+		thresholds[name] = pct
+	}
+	return thresholds, nil
+}
+
+// CompareBenchmarkReports computes the delta for every metric and flags the
+// ones whose regression exceeds thresholds.
+func CompareBenchmarkReports(old, new types.BenchmarkReport, thresholds map[string]float64) []MetricDelta {
+	var deltas []MetricDelta
+	for _, m := range compareMetrics {
+		d := MetricDelta{Name: m.name, Old: m.value(old.Summary), New: m.value(new.Summary)}
+		if m.name == "error_rate" {
+			d.Change = d.New - d.Old
+		} else if d.Old != 0 {
+			d.Change = (d.New - d.Old) / d.Old * 100
+		} else if d.New > 0 {
+			// any rise from zero is an unbounded relative change
+			d.Change = math.Inf(1)
+		}
+		if m.higher {
+			d.Worse = d.Change < 0
+		} else {
+			d.Worse = d.Change > 0
+		}
+		if limit, ok := thresholds[m.name]; ok && d.Worse && math.Abs(d.Change) > limit {
+			d.Exceeded = true
+		}
+		deltas = append(deltas, d)
+	}
+	return deltas
+}
+
+// PrintBenchmarkComparison prints a table of deltas, highlighting regressions.
+func PrintBenchmarkComparison(deltas []MetricDelta, thresholds map[string]float64) {
+	fmt.Printf("%-12s %14s %14s %10s  %s\n", "Metric", "Old", "New", "Delta", "Threshold")
+	for _, d := range deltas {
+		unit, deltaUnit := "ms", "%"
+		switch d.Name {
+		case "throughput":
+			unit = "/s"
+		case "error_rate":
+			unit, deltaUnit = "%", "pp"
+		}
+		delta := fmt.Sprintf("%+.1f%s", d.Change, deltaUnit)
+		switch {
+		case d.Exceeded:
+			delta = ColorString(delta, "red")
+		case d.Worse:
+			delta = ColorString(delta, "yellow")
+		case d.Change != 0:
+			delta = ColorString(delta, "green")
+		}
+		limit := ""
+		if l, ok := thresholds[d.Name]; ok {
+			limit = fmt.Sprintf("%.1f%s", l, deltaUnit)
+		}
+		fmt.Printf("%-12s %12.3f%-2s %12.3f%-2s %s  %s\n", d.Name, d.Old, unit, d.New, unit, padLeft(delta, 10), limit)
+	}
+
+	var exceeded []string
+	for _, d := range deltas {
+		if d.Exceeded {
+			exceeded = append(exceeded, d.Name)
+		}
+	}
+	if len(exceeded) > 0 {
+		fmt.Println()
+		Warn("Regression threshold exceeded: %s", strings.Join(exceeded, ", "))
+	}
+}
+
+func padLeft(s string, width int) string {
+	visible := utf8.RuneCountInString(ansiPattern.ReplaceAllString(s, ""))
+	if visible >= width {
+		return s
+	}
+	return strings.Repeat(" ", width-visible) + s
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"poke/types"
+)
+
+func TestCompareBenchmarkReports(t *testing.T) {
+	summary := func(mean time.Duration, failures int) types.BenchmarkReport {
+		return types.BenchmarkReport{Summary: types.BenchmarkSummary{Total: 100, Failures: failures, Latency: types.LatencyStats{Mean: mean}}}
+	}
+	thresholds := map[string]float64{"mean": 10, "error_rate": 1}
+	tests := []struct {
+		name       string
+		old, new   types.BenchmarkReport
+		metric     string
+		wantChange float64
+		wantWorse  bool
+		wantExceed bool
+	}{
+		{"within threshold", summary(100*time.Millisecond, 0), summary(105*time.Millisecond, 0), "mean", 5, true, false},
+		{"over threshold", summary(100*time.Millisecond, 0), summary(120*time.Millisecond, 0), "mean", 20, true, true},
+		{"improvement", summary(100*time.Millisecond, 0), summary(50*time.Millisecond, 0), "mean", -50, false, false},
+		{"rise from zero", summary(0, 0), summary(time.Millisecond, 0), "mean", math.Inf(1), true, true},
+		{"error rate in points", summary(0, 1), summary(0, 3), "error_rate", 2, true, true},
+		{"errors from zero", summary(0, 0), summary(0, 1), "error_rate", 1, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, d := range CompareBenchmarkReports(tt.old, tt.new, thresholds) {
+				if d.Name != tt.metric {
+					continue
+				}
+				if d.Change != tt.wantChange && math.Abs(d.Change-tt.wantChange) > 1e-9 {
+					t.Errorf("change = %v, want %v", d.Change, tt.wantChange)
+				}
+				if d.Worse != tt.wantWorse || d.Exceeded != tt.wantExceed {
+					t.Errorf("worse=%v exceeded=%v, want worse=%v exceeded=%v", d.Worse, d.Exceeded, tt.wantWorse, tt.wantExceed)
+				}
+			}
+		})
+	}
+}
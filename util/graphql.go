@@ -0,0 +1,41 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"poke/types"
+)
+
+// PrintGraphQLErrors prints each entry of a GraphQL errors array on its own line.
+func PrintGraphQLErrors(errs []types.GraphQLError) {
+	for _, e := range errs {
+		fmt.Printf("%s %s\n", ColorString("[GraphQL]", "red"), describeGraphQLError(e))
+	}
+}
+
+// graphQLError joins a response's GraphQL errors into one failure.
+func graphQLError(errs []types.GraphQLError) error {
+	joined := make([]error, 0, len(errs)+1)
+	joined = append(joined, fmt.Errorf("graphql returned %d error(s)", len(errs)))
+	for _, e := range errs {
+		joined = append(joined, errors.New("  "+describeGraphQLError(e)))
+	}
+	return errors.Join(joined...)
+}
+
+func describeGraphQLError(e types.GraphQLError) string {
+	msg := e.Message
+	if len(e.Path) > 0 {
+		parts := make([]string, len(e.Path))
+		for i, p := range e.Path {
+			parts[i] = fmt.Sprint(p)
+		}
+		msg = fmt.Sprintf("%s: %s", strings.Join(parts, "."), msg)
+	}
+	if code, ok := e.Extensions["code"]; ok {
+		msg = fmt.Sprintf("%s (%v)", msg, code)
+	}
+	return msg
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"poke/types"
+)
+
+// PrintGRPCStatus prints the status a gRPC call ended with.
+func PrintGRPCStatus(status *types.GRPCStatus) {
+	name := ColorString(status.Name, "green")
+	if status.Code != 0 {
+		name = ColorString(status.Name, "red")
+	}
+	if status.Message != "" {
+		Info("gRPC status: %s (%d): %s", name, status.Code, status.Message)
+	} else {
+		Info("gRPC status: %s (%d)", name, status.Code)
+	}
+}
+
+// assertGRPCStatus checks a call's status against want, a code name such
+// as NOT_FOUND or a number. An empty want expects OK.
+func assertGRPCStatus(status *types.GRPCStatus, want string) error {
+	if want == "" {
+		want = "OK"
+	}
+	if n, err := strconv.Atoi(want); err == nil && n == status.Code {
+		return nil
+	}
+	if strings.EqualFold(want, status.Name) {
+		return nil
+	}
+	if status.Message != "" {
+		return fmt.Errorf("expected gRPC status %s, got %s: %s", want, status.Name, status.Message)
+	}
+	return fmt.Errorf("expected gRPC status %s, got %s", want, status.Name)
+}
+
+func assertTrailers(trailers map[string][]string, want map[string][]string) error {
+	var errs []error
+	for _, k := range slices.Sorted(maps.Keys(want)) {
+		expectedVals := want[k]
+		actualVals := http.Header(trailers).Values(k)
+		if len(actualVals) == 0 {
+			errs = append(errs, fmt.Errorf("expected trailer %q to be %q, but it is missing", k, strings.Join(expectedVals, ", ")))
+			continue
+		}
+		for _, expectedVal := range expectedVals {
+			if !slices.Contains(actualVals, expectedVal) {
+				errs = append(errs, fmt.Errorf("expected trailer %q to contain %q, but it was not found", k, expectedVal))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,140 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RequestItems holds HTTPie-style arguments given after the URL.
+type RequestItems struct {
+	Headers map[string][]string
+	Query   map[string][]string
+	Fields  map[string]any // body fields, nested by dotted path
+	Form    map[string][]string
+}
+
+// itemSeparators are checked at each position longest first, so "a:=1" is
+// raw JSON rather than a header and "q==x" is a query param.
+var itemSeparators = []string{":=", "==", "=", ":"}
+
+// ParseRequestItems parses trailing arguments:
+//
+//	Header:value   request header
+//	q==term        query parameter
+//	name=x         string body field (nested with user.name=x)
+//	age:=3         raw JSON body field
+//
+// With form set, name=x items become form fields instead of JSON.
+func ParseRequestItems(items []string, form bool) (RequestItems, error) {
+	parsed := RequestItems{
+		Headers: map[string][]string{},
+		Query:   map[string][]string{},
+		Fields:  map[string]any{},
+		Form:    map[string][]string{},
+	}
+	for _, item := range items {
+		key, sep, value := splitItem(item)
+		if sep == "" || key == "" {
+			return parsed, fmt.Errorf("invalid request item %q (expected Header:value, q==term, name=value or name:=json)", item)
+		}
+		switch sep {
+		case ":":
+			parsed.Headers[key] = append(parsed.Headers[key], strings.TrimSpace(value))
+		case "==":
+			parsed.Query[key] = append(parsed.Query[key], value)
+		case "=":
+			if form {
+				parsed.Form[key] = append(parsed.Form[key], value)
+			} else if err := setPath(parsed.Fields, key, value); err != nil {
+				return parsed, err
+			}
+		case ":=":
+			if form {
+				return parsed, fmt.Errorf("raw JSON item %q cannot be sent as a form field", item)
+			}
+			var raw any
+			if err := json.Unmarshal([]byte(value), &raw); err != nil {
+				return parsed, fmt.Errorf("invalid JSON in %q: %w", item, err)
+			}
+			if err := setPath(parsed.Fields, key, raw); err != nil {
+				return parsed, err
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// splitItem finds the earliest separator in item, preferring the longest
+// one when several start at the same position.
+func splitItem(item string) (key, sep, value string) {
+	best := -1
+	for i := 0; i < len(item) && best < 0; i++ {
+		for _, s := range itemSeparators {
+			if strings.HasPrefix(item[i:], s) {
+				best, sep = i, s
+				break
+			}
+		}
+	}
+	if best < 0 {
+		return "", "", ""
+	}
+	return item[:best], sep, item[best+len(sep):]
+}
+
+// setPath assigns value at a dotted path such as "user.name", creating
+// intermediate objects as needed.
+func setPath(fields map[string]any, path string, value any) error {
+	keys := strings.Split(path, ".")
+	current := fields
+	for i, k := range keys[:len(keys)-1] {
+		next, ok := current[k]
+		if !ok {
+			child := map[string]any{}
+			current[k] = child
+			current = child
+			continue
+		}
+		child, ok := next.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot set %q: %q is already a value", path, strings.Join(keys[:i+1], "."))
+		}
+		current = child
+	}
+	current[keys[len(keys)-1]] = value
+	return nil
+}
+
+// MergeJSONBody merges fields into body, which must be empty or a JSON
+// object. Nested objects are merged; other values are replaced.
+func MergeJSONBody(body string, fields map[string]any) (string, error) {
+	if len(fields) == 0 {
+		return body, nil
+	}
+	base := map[string]any{}
+	if strings.TrimSpace(body) != "" {
+		if err := json.Unmarshal([]byte(body), &base); err != nil {
+			return "", fmt.Errorf("body fields need a JSON object body: %w", err)
+		}
+	}
+	MergeFields(base, fields)
+	out, err := json.Marshal(base)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// MergeFields merges src into dst, recursing into objects present in both.
+func MergeFields(dst, src map[string]any) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]any)
+		dstMap, dstIsMap := dst[k].(map[string]any)
+		if srcIsMap && dstIsMap {
+			MergeFields(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,333 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"poke/types"
+)
+
+// pathStep is one segment of a JSONPath: a key, an index or a wildcard.
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parsePath parses the JSONPath subset poke supports: $, .key, ['key'],
+// [n] (negative counts from the end), .* and [*].
+func parsePath(path string) ([]pathStep, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+	var steps []pathStep
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, ".."):
+			return nil, fmt.Errorf("invalid path %q: recursive descent is not supported", path)
+		case p[0] == '.' || len(steps) == 0 && p[0] != '[':
+			p = strings.TrimPrefix(p, ".")
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			key := p[:end]
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key", path)
+			}
+			steps = append(steps, pathStep{key: key, wildcard: key == "*"})
+			p = p[end:]
+		case p[0] == '[' && len(p) > 1:
+			end := strings.IndexByte(p, ']')
+			if quote := p[1:2]; quote == "'" || quote == `"` {
+				end = strings.Index(p[2:], quote+"]")
+				if end < 0 {
+					return nil, fmt.Errorf("invalid path %q: unterminated key", path)
+				}
+				steps = append(steps, pathStep{key: p[2 : 2+end]})
+				p = p[2+end+2:]
+				continue
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing ]", path)
+			}
+			inner := strings.TrimSpace(p[1:end])
+			if inner == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: bad index %q", path, inner)
+				}
+				steps = append(steps, pathStep{index: n, isIndex: true})
+			}
+			p = p[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return steps, nil
+}
+
+// EvalPath returns the values at path in doc, a decoded JSON document. multi
+// reports whether the path has a wildcard and so may select many values.
+func EvalPath(doc any, path string) (values []any, multi bool, err error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	values = []any{doc}
+	for _, step := range steps {
+		multi = multi || step.wildcard
+		var next []any
+		for _, v := range values {
+			switch node := v.(type) {
+			case map[string]any:
+				if step.wildcard {
+					keys := make([]string, 0, len(node))
+					for k := range node {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, node[k])
+					}
+				} else if child, ok := node[step.key]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []any:
+				switch {
+				case step.wildcard:
+					next = append(next, node...)
+				case step.isIndex:
+					i := step.index
+					if i < 0 {
+						i += len(node)
+					}
+					if i >= 0 && i < len(node) {
+						next = append(next, node[i])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values, multi, nil
+}
+
+func assertJSON(body []byte, checks []types.JSONAssertion) error {
+	var doc any
+	if err := decodeJSON(body, &doc); err != nil {
+		return fmt.Errorf("expected a JSON body for json assertions: %w", err)
+	}
+	var errs []error
+	for _, check := range checks {
+		if err := assertJSONPath(doc, check); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func assertJSONPath(doc any, check types.JSONAssertion) error {
+	values, multi, err := EvalPath(doc, check.Path)
+	if err != nil {
+		return err
+	}
+	found := len(values) > 0 || multi
+	var actual any
+	if multi {
+		if values == nil {
+			values = []any{}
+		}
+		actual = values
+	} else if found {
+		actual = values[0]
+	}
+
+	op := strings.ToLower(check.Op)
+	if op == "" {
+		op = "eq"
+	}
+	if op == "exists" {
+		want, ok := check.Value.(bool)
+		if !ok {
+			want = check.Value == nil
+		}
+		found = len(values) > 0
+		switch {
+		case want && !found:
+			return fmt.Errorf("expected path to exist, it was not found")
+		case !want && found:
+			return fmt.Errorf("expected path to be absent, got %s", formatJSON(actual))
+		}
+		return nil
+	}
+	if !found {
+		return fmt.Errorf("expected %s %s, path not found", op, formatJSON(check.Value))
+	}
+
+	switch op {
+	case "eq":
+		if !jsonEqual(actual, check.Value) {
+			return fmt.Errorf("expected %s, got %s", formatJSON(check.Value), formatJSON(actual))
+		}
+	case "ne":
+		if jsonEqual(actual, check.Value) {
+			return fmt.Errorf("expected anything but %s", formatJSON(check.Value))
+		}
+	case "gt", "lt":
+		got, ok := normalizeJSON(actual).(json.Number)
+		want, wantOK := normalizeJSON(check.Value).(json.Number)
+		cmp, cmpOK := compareNumbers(got, want)
+		if !ok || !wantOK || !cmpOK {
+			return fmt.Errorf("%s needs numbers, got %s and %s", op, formatJSON(actual), formatJSON(check.Value))
+		}
+		if op == "gt" && cmp <= 0 || op == "lt" && cmp >= 0 {
+			return fmt.Errorf("expected %s %s, got %s", op, formatJSON(check.Value), formatJSON(actual))
+		}
+	case "matches":
+		pattern, ok := check.Value.(string)
+		if !ok {
+			return fmt.Errorf("matches needs a regular expression string")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+		s, ok := actual.(string)
+		if !ok {
+			s = formatJSON(actual)
+		}
+		if !re.MatchString(s) {
+			return fmt.Errorf("expected to match %q, got %s", pattern, formatJSON(actual))
+		}
+	case "len":
+		want, ok := toFloat(check.Value)
+		if !ok {
+			return fmt.Errorf("len needs a number")
+		}
+		var n int
+		switch v := actual.(type) {
+		case []any:
+			n = len(v)
+		case map[string]any:
+			n = len(v)
+		case string:
+			n = len([]rune(v))
+		default:
+			return fmt.Errorf("len needs an array, object or string, got %s", formatJSON(actual))
+		}
+		if float64(n) != want {
+			return fmt.Errorf("expected length %s, got %d", formatJSON(check.Value), n)
+		}
+	default:
+		return fmt.Errorf("unknown op %q (use eq, ne, gt, lt, exists, matches or len)", check.Op)
+	}
+	return nil
+}
+
+// jsonEqual compares two values after normalising both through JSON, so
+// numbers compare by value whatever their Go type.
+func jsonEqual(a, b any) bool {
+	return equalJSON(normalizeJSON(a), normalizeJSON(b))
+}
+
+// equalJSON compares decoded JSON values, treating numbers such as 1 and 1.0
+// as equal.
+func equalJSON(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		cmp, cmpOK := compareNumbers(x, y)
+		return ok && cmpOK && cmp == 0
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			if w, ok := y[k]; !ok || !equalJSON(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalJSON(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareNumbers compares two JSON numbers exactly, however large.
+func compareNumbers(a, b json.Number) (int, bool) {
+	x, _, errA := big.ParseFloat(string(a), 10, 256, big.ToNearestEven)
+	y, _, errB := big.ParseFloat(string(b), 10, 256, big.ToNearestEven)
+	if errA != nil || errB != nil {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+func normalizeJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := decodeJSON(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// decodeJSON is json.Unmarshal that keeps numbers as json.Number, so large
+// integers such as IDs are not rounded through float64.
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid data after top-level JSON value")
+	}
+	return nil
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := normalizeJSON(v).(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func formatJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"poke/types"
+)
+
+const pathDoc = `{
+	"data": {"items": [{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": []}], "total": 2},
+	"odd key": {"a.b": true},
+	"name": "poke",
+	"big": 9007199254740993
+}`
+
+func TestEvalPath(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(pathDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path      string
+		want      []any
+		wantMulti bool
+		wantErr   string
+	}{
+		{path: "$", want: []any{doc}},
+		{path: "$.name", want: []any{"poke"}},
+		{path: "name", want: []any{"poke"}},
+		{path: "$.data.items[0].id", want: []any{1.0}},
+		{path: "$.data.items[-1].id", want: []any{2.0}},
+		{path: "$.data.items[5].id", want: nil},
+		{path: "$['odd key'][\"a.b\"]", want: []any{true}},
+		{path: "$.data.items[*].id", want: []any{1.0, 2.0}, wantMulti: true},
+		{path: "$.data.items.*.id", want: []any{1.0, 2.0}, wantMulti: true},
+		{path: "$.data.*", want: []any{doc.(map[string]any)["data"].(map[string]any)["items"], 2.0}, wantMulti: true},
+		{path: "$.missing.deeper", want: nil},
+		{path: "$.name[0]", want: nil},
+		{path: "$..id", wantErr: "recursive descent"},
+		{path: "$.data[x]", wantErr: "bad index"},
+		{path: "$.data['x", wantErr: "unterminated key"},
+		{path: "$.data[0", wantErr: "missing ]"},
+		{path: "$.data.", wantErr: "empty key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, multi, err := EvalPath(doc, tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if multi != tt.wantMulti || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v (multi %v), want %v (multi %v)", got, multi, tt.want, tt.wantMulti)
+			}
+		})
+	}
+}
+
+func TestAssertJSONPath(t *testing.T) {
+	var doc any
+	if err := decodeJSON([]byte(pathDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		check   types.JSONAssertion
+		wantErr string
+	}{
+		{check: types.JSONAssertion{Path: "$.data.total", Value: 2}},
+		{check: types.JSONAssertion{Path: "$.data.total", Op: "eq", Value: 3}, wantErr: "expected 3, got 2"},
+		{check: types.JSONAssertion{Path: "$.data.total", Value: 2.0}},
+		{check: types.JSONAssertion{Path: "$.big", Value: json.Number("9007199254740993")}},
+		{check: types.JSONAssertion{Path: "$.big", Value: json.Number("9007199254740992")}, wantErr: "got 9007199254740993"},
+		{check: types.JSONAssertion{Path: "$.big", Op: "gt", Value: json.Number("9007199254740992")}},
+		{check: types.JSONAssertion{Path: "$.data.items[*].id", Value: []any{1, 2}}},
+		{check: types.JSONAssertion{Path: "$.name", Op: "ne", Value: "poke"}, wantErr: "anything but"},
+		{check: types.JSONAssertion{Path: "$.data.total", Op: "gt", Value: 1}},
+		{check: types.JSONAssertion{Path: "$.data.total", Op: "lt", Value: 2}, wantErr: "expected lt 2"},
+		{check: types.JSONAssertion{Path: "$.name", Op: "gt", Value: 1}, wantErr: "needs numbers"},
+		{check: types.JSONAssertion{Path: "$.name", Op: "matches", Value: "^po"}},
+		{check: types.JSONAssertion{Path: "$.name", Op: "matches", Value: "("}, wantErr: "invalid regular expression"},
+		{check: types.JSONAssertion{Path: "$.data.items", Op: "len", Value: 2}},
+		{check: types.JSONAssertion{Path: "$.data.items[1].tags", Op: "len", Value: 1}, wantErr: "expected length 1, got 0"},
+		{check: types.JSONAssertion{Path: "$.data.total", Op: "len", Value: 1}, wantErr: "len needs an array"},
+		{check: types.JSONAssertion{Path: "$.name", Op: "exists"}},
+		{check: types.JSONAssertion{Path: "$.nope", Op: "exists"}, wantErr: "not found"},
+		{check: types.JSONAssertion{Path: "$.nope", Op: "exists", Value: false}},
+		{check: types.JSONAssertion{Path: "$.data.items[*].missing", Op: "exists"}, wantErr: "not found"},
+		{check: types.JSONAssertion{Path: "$.nope", Value: 1}, wantErr: "path not found"},
+		{check: types.JSONAssertion{Path: "$.name", Op: "like", Value: 1}, wantErr: "unknown op"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.check.Path+" "+tt.check.Op, func(t *testing.T) {
+			err := assertJSONPath(doc, tt.check)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSONAssertionKeepsLargeNumbers(t *testing.T) {
+	var check types.JSONAssertion
+	if err := json.Unmarshal([]byte(`{"path": "$.big", "value": 9007199254740992}`), &check); err != nil {
+		t.Fatal(err)
+	}
+	if err := assertJSON([]byte(pathDoc), []types.JSONAssertion{check}); err == nil {
+		t.Error("9007199254740992 matched 9007199254740993")
+	}
+}
+
+func TestAssertJSONReportsEveryFailure(t *testing.T) {
+	err := assertJSON([]byte(pathDoc), []types.JSONAssertion{
+		{Path: "$.name", Value: "other"},
+		{Path: "$.data.total", Value: 2},
+		{Path: "$.data.items[0].id", Op: "gt", Value: 5},
+	})
+	if err == nil {
+		t.Fatal("expected failures")
+	}
+	for _, want := range []string{`$.name: expected "other", got "poke"`, "$.data.items[0].id: expected gt 5, got 1"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q is missing %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "$.data.total") {
+		t.Errorf("%q reports a passing path", err)
+	}
+	if err := assertJSON([]byte("not json"), []types.JSONAssertion{{Path: "$"}}); err == nil {
+		t.Error("expected an error for a non-JSON body")
+	}
+}
@@ -0,0 +1,164 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	progressRedraw   = 200 * time.Millisecond // terminal refresh
+	progressLogEvery = 5 * time.Second        // plain log line interval
+	progressWindow   = 200                    // latencies kept for the rolling p95
+)
+
+// Progress reports a running benchmark. On a terminal it redraws a single
+// status line; otherwise it logs a plain line every few seconds.
+type Progress struct {
+	total    int           // expected requests, 0 when running for a duration
+	duration time.Duration // run length when total is unknown
+	start    time.Time
+	tty      bool
+
+	mu        sync.Mutex
+	completed int
+	errors    int
+	recent    []time.Duration
+	next      int
+	marks     []progressMark
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type progressMark struct {
+	at        time.Time
+	completed int
+}
+
+func NewProgress(total int, duration time.Duration) *Progress {
+	return &Progress{
+		total:    total,
+		duration: duration,
+		tty:      term.IsTerminal(int(os.Stdout.Fd())),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins reporting in the background until Stop is called.
+func (p *Progress) Start() {
+	p.start = time.Now()
+	interval := progressLogEvery
+	if p.tty {
+		interval = progressRedraw
+	}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.render()
+			case <-p.stop:
+				if p.tty {
+					fmt.Print("\r\033[K")
+				}
+				return
+			}
+		}
+	}()
+}
+
+// Record adds one finished request.
+func (p *Progress) Record(latency time.Duration, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed++
+	if failed {
+		p.errors++
+		return
+	}
+	if len(p.recent) < progressWindow {
+		p.recent = append(p.recent, latency)
+	} else {
+		p.recent[p.next] = latency
+		p.next = (p.next + 1) % progressWindow
+	}
+}
+
+// Stop ends reporting and clears the status line.
+func (p *Progress) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+func (p *Progress) render() {
+	p.mu.Lock()
+	now := time.Now()
+	elapsed := now.Sub(p.start)
+	completed, errors := p.completed, p.errors
+	sorted := slices.Clone(p.recent)
+
+	// current rate over roughly the last second
+	p.marks = append(p.marks, progressMark{at: now, completed: completed})
+	for len(p.marks) > 2 && now.Sub(p.marks[1].at) >= time.Second {
+		p.marks = p.marks[1:]
+	}
+	oldest := progressMark{at: p.start}
+	if len(p.marks) > 1 {
+		oldest = p.marks[0]
+	}
+	p.mu.Unlock()
+
+	rps := 0.0
+	if span := now.Sub(oldest.at).Seconds(); span > 0 {
+		rps = float64(completed-oldest.completed) / span
+	}
+	slices.Sort(sorted)
+	p95 := Percentile(sorted, 95)
+
+	var count, eta string
+	var fraction float64
+	switch {
+	case p.total > 0:
+		count = fmt.Sprintf("%d/%d", completed, p.total)
+		fraction = float64(completed) / float64(p.total)
+		if completed > 0 {
+			remaining := time.Duration(float64(elapsed) / float64(completed) * float64(p.total-completed))
+			eta = remaining.Round(time.Second).String()
+		}
+	case p.duration > 0:
+		count = fmt.Sprintf("%d", completed)
+		fraction = elapsed.Seconds() / p.duration.Seconds()
+		eta = max(p.duration-elapsed, 0).Round(time.Second).String()
+	}
+	if eta == "" {
+		eta = "?"
+	}
+
+	errStr := fmt.Sprintf("%d", errors)
+	if errors > 0 {
+		errStr = ColorString(errStr, "red")
+	}
+	status := fmt.Sprintf("%s  %.1f req/s  p95 %s  errors %s  eta %s",
+		count, rps, p95.Round(time.Microsecond), errStr, eta)
+
+	if p.tty {
+		fmt.Printf("\r\033[K%s %s", progressBar(fraction, 20), status)
+	} else {
+		Info("Progress: %s", status)
+	}
+}
+
+func progressBar(fraction float64, width int) string {
+	fraction = min(max(fraction, 0), 1)
+	n := int(fraction * float64(width))
+	return "[" + ColorString(strings.Repeat("=", n), "cyan") + strings.Repeat(" ", width-n) + "]"
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// schemas caches compiled schemas by their source, so repeated requests
+// compile each schema once.
+var schemas sync.Map
+
+func compileSchema(source json.RawMessage) (*jsonschema.Schema, error) {
+	if cached, ok := schemas.Load(string(source)); ok {
+		return cached.(*jsonschema.Schema), nil
+	}
+
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	var loc string
+	if err := json.Unmarshal(source, &loc); err == nil {
+		abs, err := filepath.Abs(loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema path %q: %w", loc, err)
+		}
+		loc = abs
+	} else {
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(source))
+		if err != nil {
+			return nil, fmt.Errorf("invalid inline schema: %w", err)
+		}
+		loc = "inline-schema.json"
+		if err := c.AddResource(loc, doc); err != nil {
+			return nil, fmt.Errorf("invalid inline schema: %w", err)
+		}
+	}
+	schema, err := c.Compile(loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+	schemas.Store(string(source), schema)
+	return schema, nil
+}
+
+func assertSchema(body []byte, source json.RawMessage) error {
+	schema, err := compileSchema(source)
+	if err != nil {
+		return err
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("expected a JSON body for schema validation: %w", err)
+	}
+	err = schema.Validate(doc)
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	p := message.NewPrinter(language.English)
+	var errs []error
+	for _, leaf := range schemaViolations(verr) {
+		errs = append(errs, fmt.Errorf("schema violation at %s: %s", jsonPointer(leaf.InstanceLocation), leaf.ErrorKind.LocalizedString(p)))
+	}
+	return errors.Join(errs...)
+}
+
+// schemaViolations flattens a validation error into its leaf causes, the
+// individual keywords that failed.
+func schemaViolations(verr *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(verr.Causes) == 0 {
+		return []*jsonschema.ValidationError{verr}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range verr.Causes {
+		leaves = append(leaves, schemaViolations(cause)...)
+	}
+	return leaves
+}
+
+func jsonPointer(tokens []string) string {
+	if len(tokens) == 0 {
+		return "(root)"
+	}
+	var sb strings.Builder
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, tok := range tokens {
+		sb.WriteString("/" + escaper.Replace(tok))
+	}
+	return sb.String()
+}
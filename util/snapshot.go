@@ -0,0 +1,193 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"poke/types"
+)
+
+// Snapshot is the golden response recorded next to a saved request.
+type Snapshot struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        any    `json:"body"` // decoded JSON, or the raw text for other bodies
+}
+
+// SnapshotPath returns the snapshot file for a request file, e.g.
+// users.json -> users.snap.json.
+func SnapshotPath(requestPath string) string {
+	return strings.TrimSuffix(requestPath, ".json") + ".snap.json"
+}
+
+func snapshotOf(resp *types.PokeResponse) Snapshot {
+	snap := Snapshot{Status: resp.StatusCode, ContentType: resp.ContentType, Body: string(resp.Body)}
+	var doc any
+	if len(resp.Body) > 0 && decodeJSON(resp.Body, &doc) == nil {
+		snap.Body = doc
+	}
+	return snap
+}
+
+// WriteSnapshot records resp as the golden response at path.
+func WriteSnapshot(path string, resp *types.PokeResponse) error {
+	data, err := json.MarshalIndent(snapshotOf(resp), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// CompareSnapshot diffs resp against the snapshot at path, skipping the body
+// fields selected by the ignore paths. The error lists every difference.
+func CompareSnapshot(path string, resp *types.PokeResponse, ignore []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var want Snapshot
+	if err := decodeJSON(data, &want); err != nil {
+		return fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	got := snapshotOf(resp)
+
+	for _, p := range ignore {
+		steps, err := parsePath(p)
+		if err != nil {
+			return fmt.Errorf("invalid snapshot ignore: %w", err)
+		}
+		want.Body = removePath(want.Body, steps)
+		got.Body = removePath(got.Body, steps)
+	}
+
+	var diffs []string
+	if want.Status != got.Status {
+		diffs = append(diffs, diffChanged("status", want.Status, got.Status))
+	}
+	if want.ContentType != got.ContentType {
+		diffs = append(diffs, diffChanged("content_type", want.ContentType, got.ContentType))
+	}
+	diffJSON("$", want.Body, got.Body, &diffs)
+	if len(diffs) == 0 {
+		return nil
+	}
+	errs := []error{fmt.Errorf("response differs from snapshot %s (%s removed, %s added, %s changed)",
+		path, ColorString("-", "red"), ColorString("+", "green"), ColorString("~", "yellow"))}
+	for _, d := range diffs {
+		errs = append(errs, errors.New("  "+d))
+	}
+	return errors.Join(errs...)
+}
+
+// diffJSON appends a line for every difference between two decoded JSON
+// values, descending into objects and arrays.
+func diffJSON(path string, want, got any, diffs *[]string) {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := slices.Sorted(maps.Keys(w))
+		for _, k := range slices.Sorted(maps.Keys(g)) {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			child := childPath(path, k)
+			wv, inWant := w[k]
+			gv, inGot := g[k]
+			switch {
+			case !inGot:
+				*diffs = append(*diffs, ColorString("- "+child+": "+formatJSON(wv), "red"))
+			case !inWant:
+				*diffs = append(*diffs, ColorString("+ "+child+": "+formatJSON(gv), "green"))
+			default:
+				diffJSON(child, wv, gv, diffs)
+			}
+		}
+		return
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			break
+		}
+		for i := range max(len(w), len(g)) {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(g):
+				*diffs = append(*diffs, ColorString("- "+child+": "+formatJSON(w[i]), "red"))
+			case i >= len(w):
+				*diffs = append(*diffs, ColorString("+ "+child+": "+formatJSON(g[i]), "green"))
+			default:
+				diffJSON(child, w[i], g[i], diffs)
+			}
+		}
+		return
+	}
+	if !equalJSON(want, got) {
+		*diffs = append(*diffs, diffChanged(path, want, got))
+	}
+}
+
+func diffChanged(path string, want, got any) string {
+	return ColorString("~ "+path+": ", "yellow") + ColorString(formatJSON(want), "red") + " -> " + ColorString(formatJSON(got), "green")
+}
+
+func childPath(path, key string) string {
+	if key == "" {
+		return path + `[""]`
+	}
+	for _, r := range key {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return path + "[" + strconv.Quote(key) + "]"
+		}
+	}
+	return path + "." + key
+}
+
+// removePath deletes the values selected by steps from doc: object keys are
+// removed and array elements are replaced with null so indexes stay stable.
+func removePath(doc any, steps []pathStep) any {
+	if len(steps) == 0 {
+		return nil
+	}
+	step, rest := steps[0], steps[1:]
+	switch node := doc.(type) {
+	case map[string]any:
+		for k, v := range node {
+			if step.wildcard || !step.isIndex && k == step.key {
+				if len(rest) == 0 {
+					delete(node, k)
+				} else {
+					node[k] = removePath(v, rest)
+				}
+			}
+		}
+	case []any:
+		for i, v := range node {
+			idx := step.index
+			if idx < 0 {
+				idx += len(node)
+			}
+			if step.wildcard || step.isIndex && i == idx {
+				if len(rest) == 0 {
+					node[i] = nil
+				} else {
+					node[i] = removePath(v, rest)
+				}
+			}
+		}
+	}
+	return doc
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"poke/types"
+)
+
+func TestCompareSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "req.snap.json")
+	golden := &types.PokeResponse{
+		StatusCode:  200,
+		ContentType: "application/json",
+		Body:        []byte(`{"id": 1, "name": "a", "created_at": "2024-01-01", "items": [{"id": 1}, {"id": 2}]}`),
+	}
+	if err := WriteSnapshot(path, golden); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		resp   types.PokeResponse
+		ignore []string
+		want   []string // substrings of the diff, nil for a match
+	}{
+		{name: "identical", resp: *golden},
+		{
+			name: "changed, added and removed fields",
+			resp: types.PokeResponse{StatusCode: 200, ContentType: "application/json",
+				Body: []byte(`{"id": 1, "name": "b", "created_at": "2024-01-01", "items": [{"id": 1}], "extra": true}`)},
+			want: []string{`$.name: `, `"a"`, `"b"`, `- $.items[1]: {"id":2}`, `+ $.extra: true`},
+		},
+		{
+			name: "ignored fields",
+			resp: types.PokeResponse{StatusCode: 200, ContentType: "application/json",
+				Body: []byte(`{"id": 1, "name": "a", "created_at": "2025-06-30", "items": [{"id": 7}, {"id": 8}]}`)},
+			ignore: []string{"$.created_at", "$.items[*].id"},
+		},
+		{
+			name: "status and content type",
+			resp: types.PokeResponse{StatusCode: 503, ContentType: "text/html", Body: golden.Body},
+			want: []string{"status: ", "503", "content_type: ", "text/html"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CompareSnapshot(path, &tt.resp, tt.ignore)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected diff: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected a diff")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("diff %q is missing %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareSnapshotKeepsLargeNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "req.snap.json")
+	if err := WriteSnapshot(path, &types.PokeResponse{StatusCode: 200, Body: []byte(`{"id": 9007199254740993}`)}); err != nil {
+		t.Fatal(err)
+	}
+	err := CompareSnapshot(path, &types.PokeResponse{StatusCode: 200, Body: []byte(`{"id": 9007199254740992}`)}, nil)
+	if err == nil || !strings.Contains(err.Error(), "9007199254740993") {
+		t.Errorf("got %v, want a diff on $.id", err)
+	}
+	if err := CompareSnapshot(path, &types.PokeResponse{StatusCode: 200, Body: []byte(`{"id": 9007199254740993}`)}, nil); err != nil {
+		t.Errorf("unexpected diff: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package util
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"poke/types"
+)
+
+// ComputeLatencyStats returns min/max/mean, standard deviation and
+// nearest-rank percentiles for durations.
+func ComputeLatencyStats(durations []time.Duration) types.LatencyStats {
+	stats := types.LatencyStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var sum float64
+	for _, d := range sorted {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(sorted))
+	var sq float64
+	for _, d := range sorted {
+		sq += (float64(d) - mean) * (float64(d) - mean)
+	}
+
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.Mean = time.Duration(mean)
+	stats.StdDev = time.Duration(math.Sqrt(sq / float64(len(sorted))))
+	stats.P50 = Percentile(sorted, 50)
+	stats.P90 = Percentile(sorted, 90)
+	stats.P95 = Percentile(sorted, 95)
+	stats.P99 = Percentile(sorted, 99)
+	stats.P999 = Percentile(sorted, 99.9)
+	return stats
+}
+
+// Percentile returns the nearest-rank percentile p (0-100) of sorted.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
+
+// HistogramBucket counts the durations up to and including Upper.
+type HistogramBucket struct {
+	Upper time.Duration
+	Count int
+}
+
+// Histogram splits durations into n equal-width buckets between min and max.
+func Histogram(durations []time.Duration, n int) []HistogramBucket {
+	if len(durations) == 0 || n < 1 {
+		return nil
+	}
+	lo, hi := slices.Min(durations), slices.Max(durations)
+	width := (hi - lo) / time.Duration(n)
+	if width <= 0 {
+		return []HistogramBucket{{Upper: hi, Count: len(durations)}}
+	}
+	buckets := make([]HistogramBucket, n)
+	for i := range buckets {
+		buckets[i].Upper = lo + width*time.Duration(i+1)
+	}
+	buckets[n-1].Upper = hi
+	for _, d := range durations {
+		i := min(int((d-lo)/width), n-1)
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// ClassifyError buckets a failed request into a short, stable error class.
+// A response with an error means the request went through but failed its
+// assertions.
+func ClassifyError(resp *types.PokeResponse, err error) string {
+	if resp != nil {
+		if len(resp.GraphQL) > 0 {
+			return "graphql error"
+		}
+		return "assertion failure"
+	}
+	if err == nil {
+		return "unknown"
+	}
+
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns failure"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection reset"
+	case errors.As(err, &unknownAuthority), errors.As(err, &hostname),
+		strings.Contains(err.Error(), "tls:"):
+		return "tls error"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection closed"
+	}
+	return "other"
+}
+
+// ParseRate parses a request rate such as "50/s", "600/m" or "100" (per
+// second) into requests per second.
+func ParseRate(s string) (float64, error) {
+	num, unit, _ := strings.Cut(strings.TrimSpace(s), "/")
+	rate, err := strconv.ParseFloat(num, 64)
+	if err != nil || rate <= 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	switch strings.ToLower(unit) {
+	case "", "s", "sec":
+		return rate, nil
+	case "m", "min":
+		return rate / 60, nil
+	case "h", "hr":
+		return rate / 3600, nil
+	}
+	return 0, fmt.Errorf("invalid rate unit %q (use s, m or h)", unit)
+}
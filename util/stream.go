@@ -0,0 +1,38 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"poke/types"
+)
+
+// PrintEvent prints one stream message as it arrives, pretty-printing JSON data.
+func PrintEvent(ev types.StreamEvent) {
+	header := ColorString(ev.Event, "cyan")
+	if ev.ID != "" {
+		header += ColorString(" id="+ev.ID, "magenta")
+	}
+	fmt.Println(header)
+	PrintBody([]byte(ev.Data), "application/json")
+}
+
+func assertEvents(events []types.StreamEvent, want *types.StreamAssertions) error {
+	var errs []error
+	if len(events) < want.MinCount {
+		errs = append(errs, fmt.Errorf("expected at least %d events, got %d", want.MinCount, len(events)))
+	}
+	for _, typ := range want.Types {
+		if !slices.ContainsFunc(events, func(ev types.StreamEvent) bool { return ev.Event == typ }) {
+			errs = append(errs, fmt.Errorf("expected an event of type %q, none received", typ))
+		}
+	}
+	for _, s := range want.Contains {
+		if !slices.ContainsFunc(events, func(ev types.StreamEvent) bool { return strings.Contains(ev.Data, s) }) {
+			errs = append(errs, fmt.Errorf("expected an event containing %q, none received", s))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,183 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+
+	"poke/types"
+)
+
+// TransferProgress reports bytes moving through an upload or download. Like
+// Progress it redraws a status line on a terminal and logs plain lines otherwise.
+type TransferProgress struct {
+	label string
+	total int64 // expected bytes, -1 when unknown
+	start time.Time
+	tty   bool
+	done  atomic.Int64
+
+	once    sync.Once
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func NewTransferProgress(label string, total int64) *TransferProgress {
+	return &TransferProgress{
+		label:   label,
+		total:   total,
+		tty:     term.IsTerminal(int(os.Stdout.Fd())),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start begins reporting in the background until Stop is called.
+func (p *TransferProgress) Start() {
+	p.start = time.Now()
+	interval := progressLogEvery
+	if p.tty {
+		interval = progressRedraw
+	}
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.render()
+			case <-p.stop:
+				if p.tty {
+					fmt.Print("\r\033[K")
+				}
+				return
+			}
+		}
+	}()
+}
+
+// Add records n more bytes transferred.
+func (p *TransferProgress) Add(n int64) {
+	p.done.Add(n)
+}
+
+// Stop ends reporting and clears the status line. It is safe to call more than once.
+func (p *TransferProgress) Stop() {
+	p.once.Do(func() {
+		close(p.stop)
+		<-p.stopped
+	})
+}
+
+// Reader wraps rc so reads are counted, stopping the progress at EOF or Close.
+func (p *TransferProgress) Reader(rc io.ReadCloser) io.ReadCloser {
+	return &progressReader{rc: rc, p: p}
+}
+
+type progressReader struct {
+	rc io.ReadCloser
+	p  *TransferProgress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.rc.Read(b)
+	r.p.Add(int64(n))
+	if err == io.EOF {
+		r.p.Stop()
+	}
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	r.p.Stop()
+	return r.rc.Close()
+}
+
+func (p *TransferProgress) render() {
+	done := p.done.Load()
+	elapsed := time.Since(p.start)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(done) / elapsed.Seconds()
+	}
+
+	status := fmt.Sprintf("%s  %s/s", FormatBytes(done), FormatBytes(int64(rate)))
+	fraction := 0.0
+	if p.total > 0 {
+		fraction = float64(done) / float64(p.total)
+		eta := "?"
+		if rate > 0 {
+			eta = time.Duration(float64(p.total-done) / rate * float64(time.Second)).Round(time.Second).String()
+		}
+		status = fmt.Sprintf("%3.0f%%  %s / %s  %s/s  eta %s",
+			fraction*100, FormatBytes(done), FormatBytes(p.total), FormatBytes(int64(rate)), eta)
+	}
+
+	switch {
+	case !p.tty:
+		Info("%s: %s", p.label, status)
+	case p.total > 0:
+		fmt.Printf("\r\033[K%s %s %s", p.label, progressBar(fraction, 20), status)
+	default:
+		fmt.Printf("\r\033[K%s %s", p.label, status)
+	}
+}
+
+// PrintDownload reports a response whose body was written to a file.
+func PrintDownload(resp *types.PokeResponse) {
+	switch {
+	case resp.Bytes == 0 && resp.Resumed > 0:
+		Info("%s already complete (%s), nothing to resume", resp.Output, FormatBytes(resp.Resumed))
+	case resp.Resumed > 0:
+		Info("%s: resumed %s at %s, wrote %s", ColorStatus(resp.StatusCode), resp.Output, FormatBytes(resp.Resumed), FormatBytes(resp.Bytes))
+	default:
+		Info("%s: saved %s to %s", ColorStatus(resp.StatusCode), FormatBytes(resp.Bytes), resp.Output)
+	}
+}
+
+// ParseSize parses a byte count such as "1048576", "512k", "10MB" or "1GiB".
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	split := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	num, unit := s, ""
+	if split >= 0 {
+		num, unit = s[:split], strings.ToLower(strings.TrimSpace(s[split:]))
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 512k, 10MB or 1GiB)", s)
+	}
+	multipliers := map[string]float64{
+		"": 1, "b": 1,
+		"k": 1e3, "kb": 1e3, "kib": 1 << 10,
+		"m": 1e6, "mb": 1e6, "mib": 1 << 20,
+		"g": 1e9, "gb": 1e9, "gib": 1 << 30,
+	}
+	m, ok := multipliers[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q in %q", unit, s)
+	}
+	return int64(n * m), nil
+}
+
+// FormatBytes renders n as a short human-readable size such as "12.3 MB".
+func FormatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
@@ -2,10 +2,12 @@ package util
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -21,8 +23,18 @@ import (
 	"github.com/fatih/color"
 )
 
-func ReadResponse(resp *http.Response) ([]byte, error) {
-	return io.ReadAll(resp.Body)
+// ReadBody buffers r. A positive limit stops reading after that many bytes;
+// truncated reports the cut.
+func ReadBody(r io.Reader, limit int64) (body []byte, truncated bool, err error) {
+	if limit <= 0 {
+		body, err = io.ReadAll(r)
+		return body, false, err
+	}
+	body, err = io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(body)) > limit {
+		return body[:limit], true, err
+	}
+	return body, false, err
 }
 
 func PrintResponseVerbose(resp *types.PokeResponse, req *types.PokeRequest, duration time.Duration) {
@@ -30,16 +42,15 @@ func PrintResponseVerbose(resp *types.PokeResponse, req *types.PokeRequest, dura
 
 	fmt.Printf("-> %s %s\n", req.Method, req.Path)
 	fmt.Printf("-> Host: %s\n", req.Host)
+	if resp.Proxy != "" {
+		fmt.Printf("-> Proxy: %s\n", resp.Proxy)
+	}
 	for k, v := range req.Headers {
 		fmt.Printf("-> %s: %s\n", k, strings.Join(v, ", "))
 	}
 	fmt.Println("->")
 
-	contentType := ""
-	if vals, exists := req.Headers["Content-Type"]; exists && len(vals) > 0 {
-		contentType = vals[0]
-	}
-	PrintBody([]byte(req.Body), contentType)
+	PrintRequestBody(req)
 
 	fmt.Println()
 	fmt.Printf("<- %s\n", status)
@@ -48,6 +59,60 @@ func PrintResponseVerbose(resp *types.PokeResponse, req *types.PokeRequest, dura
 	}
 	fmt.Println("<-")
 	PrintBody(resp.Body, resp.ContentType)
+	for k, v := range resp.Trailers {
+		fmt.Printf("<- (trailer) %s: %s\n", k, strings.Join(v, ", "))
+	}
+	PrintTimings(resp.Timings)
+}
+
+// PrintTimings draws a waterfall of the request phases, each bar starting
+// where the previous phase ended.
+func PrintTimings(t *types.Timings) {
+	if t == nil || t.Total <= 0 {
+		return
+	}
+	phases := timingPhases(*t)
+	span := t.Total
+	var sum time.Duration
+	for _, p := range phases {
+		sum += p.d
+	}
+	span = max(span, sum)
+
+	const width = 40
+	scale := func(d time.Duration) int {
+		return int(float64(d) / float64(span) * width)
+	}
+
+	fmt.Println()
+	var offset time.Duration
+	for _, p := range phases {
+		start := min(scale(offset), width)
+		n := scale(p.d)
+		if p.d > 0 && n == 0 {
+			n = 1
+		}
+		n = min(n, width-start)
+		bar := strings.Repeat(" ", start) + ColorString(strings.Repeat("█", n), "cyan") + strings.Repeat(" ", width-start-n)
+		fmt.Printf("   %-9s %12v |%s|\n", p.name, p.d.Round(time.Microsecond), bar)
+		offset += p.d
+	}
+	fmt.Printf("   %-9s %12v\n", "Total", t.Total.Round(time.Microsecond))
+}
+
+type timingPhase struct {
+	name string
+	d    time.Duration
+}
+
+func timingPhases(t types.Timings) []timingPhase {
+	return []timingPhase{
+		{"DNS", t.DNS},
+		{"Connect", t.Connect},
+		{"TLS", t.TLS},
+		{"TTFB", t.TTFB},
+		{"Transfer", t.Transfer},
+	}
 }
 
 func PrintBody(body []byte, contentType string) {
@@ -67,67 +132,81 @@ func PrintBody(body []byte, contentType string) {
 	}
 }
 
-func PrintBenchmarkResults(res types.BenchmarkResult, totalTime float64, req *types.PokeRequest) {
-	fmt.Println("╭──────────── Poke Benchmark ────────────╮")
-	fmt.Printf("│ Requests       %-23d │\n", res.Total)
-	fmt.Printf("│ Success        %-32s │\n", ColorString(fmt.Sprintf("%d", res.Successes), "green"))
-	fmt.Printf("│ Failures       %-32s │\n", ColorString(fmt.Sprintf("%d", res.Failures), "red"))
-	fmt.Printf("│ Total time     %-.2fs%18s │\n", totalTime, "")
-
-	if len(res.Durations) == 0 {
-		fmt.Printf("│ Avg duration   %-23s │\n", "N/A")
-		fmt.Printf("│ Min            %-23s │\n", "N/A")
-		fmt.Printf("│ Max            %-23s │\n", "N/A")
-	} else {
-		min, max := res.Durations[0], res.Durations[0]
-		var sum time.Duration
-		for _, d := range res.Durations {
-			if d < min {
-				min = d
-			}
-			if d > max {
-				max = d
-			}
-			sum += d
+// AssertResponse checks resp against every assertion and reports all
+// failures together, not just the first.
+func AssertResponse(resp *types.PokeResponse, assertions *types.Assertions) (bool, error) {
+	var errs []error
+	fail := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
 		}
-		avg := sum / time.Duration(len(res.Durations))
-		fmt.Printf("│ Avg duration   %-23v │\n", avg)
-		fmt.Printf("│ Min            %-32s │\n", ColorString(min.String(), "blue"))
-		fmt.Printf("│ Max            %-32s │\n", ColorString(max.String(), "yellow"))
 	}
 
-	throughput := float64(res.Total) / totalTime
-	fmt.Printf("│ Throughput     %-23.2f │\n", throughput)
-	fmt.Printf("│ Workers        %-23d │\n", req.Workers)
-	fmt.Println("╰────────────────────────────────────────╯")
-}
-
-func AssertResponse(resp *types.PokeResponse, assertions *types.Assertions) (bool, error) {
 	if assertions.Status != 0 && resp.StatusCode != assertions.Status {
-		return false, fmt.Errorf("expected status %d, got %d", assertions.Status, resp.StatusCode)
+		fail(fmt.Errorf("expected status %d, got %d", assertions.Status, resp.StatusCode))
+	}
+	if assertions.StatusRange != "" {
+		fail(assertStatusRange(resp.StatusCode, assertions.StatusRange))
 	}
+	if assertions.MaxDuration > 0 && resp.Duration > time.Duration(assertions.MaxDuration) {
+		fail(fmt.Errorf("expected a response within %s, took %s", time.Duration(assertions.MaxDuration), resp.Duration.Round(time.Millisecond)))
+	}
+	fail(assertBodySize(resp, assertions))
 
 	if assertions.BodyContains != "" && !strings.Contains(string(resp.Body), assertions.BodyContains) {
-		return false, fmt.Errorf("expected body to contain %q, got %q", assertions.BodyContains, string(resp.Body))
+		fail(fmt.Errorf("expected body to contain %q, got %q", assertions.BodyContains, string(resp.Body)))
+	}
+	if assertions.BodyNotContains != "" && strings.Contains(string(resp.Body), assertions.BodyNotContains) {
+		fail(fmt.Errorf("expected body not to contain %q", assertions.BodyNotContains))
+	}
+	if assertions.BodyMatches != "" {
+		fail(assertMatches("body", string(resp.Body), assertions.BodyMatches))
+	}
+	if len(assertions.JSON) > 0 {
+		fail(assertJSON(resp.Body, assertions.JSON))
+	}
+	if len(assertions.Schema) > 0 {
+		fail(assertSchema(resp.Body, assertions.Schema))
+	}
+
+	if len(resp.GraphQL) > 0 && !assertions.AllowGraphQLErrors {
+		fail(graphQLError(resp.GraphQL))
+	}
+	if resp.GRPC != nil {
+		fail(assertGRPCStatus(resp.GRPC, assertions.GRPCStatus))
+	}
+	fail(assertTrailers(resp.Trailers, assertions.Trailers))
+	if assertions.Events != nil {
+		fail(assertEvents(resp.Events, assertions.Events))
 	}
 
-	for k, expectedVals := range assertions.Headers {
+	for _, k := range slices.Sorted(maps.Keys(assertions.Headers)) {
+		expectedVals := assertions.Headers[k]
 		actualVals, ok := resp.Headers[k]
 		if !ok {
-			return false, fmt.Errorf("expected header %q to be %q, but it is missing", k, strings.Join(expectedVals, ", "))
+			fail(fmt.Errorf("expected header %q to be %q, but it is missing", k, strings.Join(expectedVals, ", ")))
+			continue
 		}
 		if len(actualVals) == 0 {
-			return false, fmt.Errorf("expected header %q to be %q, but it is empty", k, strings.Join(expectedVals, ", "))
+			fail(fmt.Errorf("expected header %q to be %q, but it is empty", k, strings.Join(expectedVals, ", ")))
+			continue
 		}
 		for _, expectedVal := range expectedVals {
 			found := slices.Contains(actualVals, expectedVal)
 			if !found {
-				return false, fmt.Errorf("expected header %q to contain %q, but it was not found", k, expectedVal)
+				fail(fmt.Errorf("expected header %q to contain %q, but it was not found", k, expectedVal))
 			}
 		}
 	}
+	fail(assertHeaderMatches(resp.Headers, assertions.HeaderMatches))
+	for _, k := range assertions.HeaderAbsent {
+		if vals := http.Header(resp.Headers).Values(k); len(vals) > 0 {
+			fail(fmt.Errorf("expected header %q to be absent, got %q", k, strings.Join(vals, ", ")))
+		}
+	}
 
-	return true, nil
+	err := errors.Join(errs...)
+	return err == nil, err
 }
 
 func ParseHeaders(headerStr string) map[string][]string {
@@ -208,11 +287,6 @@ func DumpRequest(req *types.PokeRequest) {
 		return
 	}
 
-	contentType := ""
-	if vals, exists := req.Headers["Content-Type"]; exists && len(vals) > 0 {
-		contentType = vals[0]
-	}
-
 	fmt.Printf("-> %s %s\n", req.Method, req.Path)
 	fmt.Printf("-> Host: %s\n", req.Host)
 	if len(req.Headers) > 0 {
@@ -221,11 +295,71 @@ func DumpRequest(req *types.PokeRequest) {
 		}
 	}
 	fmt.Println("->")
-	if req.BodyFile != "" {
-		fmt.Printf("file:%s", req.BodyFile)
-	} else if len(req.Body) > 0 {
+	PrintRequestBody(req)
+}
+
+// PrintRequestBody prints the outgoing body, summarising form fields and
+// file references instead of their contents.
+func PrintRequestBody(req *types.PokeRequest) {
+	contentType := ""
+	if vals, exists := req.Headers["Content-Type"]; exists && len(vals) > 0 {
+		contentType = vals[0]
+	}
+
+	switch {
+	case len(req.Multipart) > 0:
+		for _, part := range req.Multipart {
+			if part.File != "" {
+				fmt.Printf("%s=@%s\n", part.Name, part.File)
+			} else {
+				fmt.Printf("%s=%s\n", part.Name, part.Value)
+			}
+		}
+	case len(req.Form) > 0:
+		for _, k := range slices.Sorted(maps.Keys(req.Form)) {
+			for _, v := range req.Form[k] {
+				fmt.Printf("%s=%s\n", k, v)
+			}
+		}
+	case len(req.Body) > 0:
 		PrintBody([]byte(req.Body), contentType)
+	case req.BodyFile != "":
+		fmt.Printf("file:%s\n", req.BodyFile)
+	case req.BodyStdin:
+		fmt.Println("stdin")
+	}
+}
+
+// ParseFormField parses a -F argument: "name=value", or "name=@path" with
+// optional ";type=mime/type" and ";filename=name" attributes for files.
+func ParseFormField(s string) (types.FormPart, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return types.FormPart{}, fmt.Errorf("invalid form field %q (expected name=value or name=@file)", s)
+	}
+	part := types.FormPart{Name: name}
+	if !strings.HasPrefix(value, "@") {
+		part.Value = value
+		return part, nil
 	}
+
+	attrs := strings.Split(value[1:], ";")
+	part.File = attrs[0]
+	for _, attr := range attrs[1:] {
+		k, v, _ := strings.Cut(attr, "=")
+		switch strings.TrimSpace(k) {
+		case "type":
+			part.ContentType = strings.TrimSpace(v)
+		case "filename":
+			part.Filename = strings.TrimSpace(v)
+		default:
+			return types.FormPart{}, fmt.Errorf("unknown form field attribute %q", k)
+		}
+	}
+	if part.File == "" {
+		return types.FormPart{}, fmt.Errorf("invalid form field %q: missing file path", s)
+	}
+	return part, nil
 }
 
 func Backoff(base, max time.Duration, attempt int) time.Duration {
@@ -244,6 +378,21 @@ func DetectContentType(req *types.PokeRequest) string {
 		}
 	}
 
+	// form bodies dictate their own type; multipart keeps a boundary the
+	// user supplied, otherwise one is generated for the body writer to use
+	if len(req.Multipart) > 0 {
+		if mediaType, params, err := mime.ParseMediaType(ct); err == nil && mediaType == "multipart/form-data" && params["boundary"] != "" {
+			req.Boundary = params["boundary"]
+		}
+		if req.Boundary == "" {
+			req.Boundary = multipart.NewWriter(io.Discard).Boundary()
+		}
+		return mime.FormatMediaType("multipart/form-data", map[string]string{"boundary": req.Boundary})
+	}
+	if len(req.Form) > 0 {
+		return "application/x-www-form-urlencoded"
+	}
+
 	// else try to infer it from the file name
 	if ct == "" && req.BodyFile != "" {
 		ct = mime.TypeByExtension(filepath.Ext(req.BodyFile))